@@ -10,12 +10,15 @@ import (
 	"time"
 
 	jwt "github.com/pusher/jwt-go"
+	"github.com/pusher/pusher-platform-go/metrics"
 )
 
 const (
 	defaultTokenExpiry         = 24 * time.Hour
 	clientCredentialsGrantType = "client_credentials"
 	tokenType                  = "Bearer"
+	invalidGrantTypeError      = "token_provider/invalid_grant_type"
+	signingError               = "token_provider/signing_failed"
 )
 
 // Authenticator specifies the public facing interface
@@ -29,14 +32,33 @@ type authenticator struct {
 	instanceID string
 	keyID      string
 	keySecret  string
+
+	metrics metrics.Metrics
+	labels  metrics.Labels
 }
 
 // New returns a new instance of an authenticator that conforms to the Authenticator interface.
 func New(instanceID, keyID, keySecret string) Authenticator {
 	return &authenticator{
-		instanceID,
-		keyID,
-		keySecret,
+		instanceID: instanceID,
+		keyID:      keyID,
+		keySecret:  keySecret,
+	}
+}
+
+// NewWithMetrics returns a new authenticator that reports issued
+// and rejected tokens to the metrics with the given labels.
+func NewWithMetrics(
+	instanceID, keyID, keySecret string,
+	m metrics.Metrics,
+	labels metrics.Labels,
+) Authenticator {
+	return &authenticator{
+		instanceID: instanceID,
+		keyID:      keyID,
+		keySecret:  keySecret,
+		metrics:    m,
+		labels:     labels,
 	}
 }
 
@@ -47,10 +69,11 @@ func (auth *authenticator) Do(
 ) (*Response, error) {
 	grantType := payload.GrantType
 	if grantType != clientCredentialsGrantType {
+		auth.observe(options.Su, invalidGrantTypeError)
 		return &Response{
 			Status: http.StatusUnprocessableEntity,
 			Body: &ErrorBody{
-				ErrorType:        "token_provider/invalid_grant_type",
+				ErrorType:        invalidGrantTypeError,
 				ErrorDescription: fmt.Sprintf("The grant type provided %s is unsupported", grantType),
 			},
 		}, nil
@@ -104,8 +127,10 @@ func (auth *authenticator) GenerateAccessToken(options Options) (TokenWithExpiry
 
 	signedToken, err := signToken(auth.keySecret, tokenClaims)
 	if err != nil {
+		auth.observe(options.Su, signingError)
 		return TokenWithExpiry{}, err
 	}
+	auth.observe(options.Su, "")
 
 	return TokenWithExpiry{
 		Token:     signedToken,
@@ -113,6 +138,19 @@ func (auth *authenticator) GenerateAccessToken(options Options) (TokenWithExpiry
 	}, nil
 }
 
+// observe reports an issued token, or a rejected one if an error type is given.
+func (auth *authenticator) observe(su bool, errorType string) {
+	if auth.metrics == nil {
+		return
+	}
+
+	auth.metrics.ObserveAuthentication(metrics.AuthenticationObservation{
+		Labels:    auth.labels,
+		Su:        su,
+		ErrorType: errorType,
+	})
+}
+
 // Signs a token with the secret key.
 func signToken(
 	keySecret string,
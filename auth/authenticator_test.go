@@ -3,10 +3,12 @@ package auth
 import (
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	jwt "github.com/pusher/jwt-go"
+	"github.com/pusher/pusher-platform-go/metrics"
 )
 
 func TestAuthenticateSuccess(t *testing.T) {
@@ -213,6 +215,33 @@ func TestAuthenticateFailure(t *testing.T) {
 	}
 }
 
+func TestAuthenticatorMetrics(t *testing.T) {
+	m := metrics.NewInMemory()
+	labels := metrics.Labels{Service: "test_service", Version: "v1", Cluster: "local"}
+	authenticator := NewWithMetrics("instance-id", "key", "secret", m, labels)
+
+	authenticator.Do(Payload{GrantType: "client_credentials"}, Options{})
+	authenticator.Do(Payload{GrantType: "client_credentials"}, Options{Su: true})
+	authenticator.GenerateAccessToken(Options{Su: true})
+	authenticator.Do(Payload{GrantType: "custom_grant_type"}, Options{})
+
+	var output strings.Builder
+	if err := m.WritePrometheus(&output); err != nil {
+		t.Fatalf("Expected no error when writing metrics, but got %+v", err)
+	}
+
+	expected := []string{
+		`pusher_platform_tokens_issued_total{service="test_service",version="v1",cluster="local",su="false"} 1`,
+		`pusher_platform_tokens_issued_total{service="test_service",version="v1",cluster="local",su="true"} 2`,
+		`pusher_platform_authentication_rejects_total{service="test_service",version="v1",cluster="local",error_type="token_provider/invalid_grant_type"} 1`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(output.String(), line) {
+			t.Fatalf("Expected metrics to contain `%s`, but got:\n%s", line, output.String())
+		}
+	}
+}
+
 // Helpers
 
 func parseToken(token string) (*jwt.Token, error) {
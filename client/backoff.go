@@ -0,0 +1,98 @@
+package client
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const (
+	defaultInitialBackoff    = 500 * time.Millisecond
+	defaultMaxBackoff        = 30 * time.Second
+	defaultBackoffMultiplier = 2
+)
+
+// Backoff configures the delay between consecutive attempts.
+//
+// The delay grows exponentially from Initial by Multiplier up to Max.
+// Zero values are replaced by defaults of 500ms, 30s and 2 respectively.
+type Backoff struct {
+	Initial    time.Duration // Delay before the first retry
+	Max        time.Duration // Upper bound of the delay
+	Multiplier float64       // Factor by which the delay grows
+	Jitter     bool          // Randomise delays to avoid synchronised retries
+}
+
+// Delay returns the delay before the given attempt, starting at 1.
+func (b Backoff) Delay(attempt int) time.Duration {
+	initial := b.Initial
+	if initial <= 0 {
+		initial = defaultInitialBackoff
+	}
+
+	max := b.Max
+	if max <= 0 {
+		max = defaultMaxBackoff
+	}
+
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = defaultBackoffMultiplier
+	}
+
+	if attempt < 1 {
+		attempt = 1
+	}
+
+	delay := float64(initial) * math.Pow(multiplier, float64(attempt-1))
+	if delay > float64(max) {
+		delay = float64(max)
+	}
+
+	if b.Jitter {
+		delay = delay/2 + rand.Float64()*delay/2
+	}
+
+	return time.Duration(delay)
+}
+
+// sleep waits for the given duration, returning early with
+// the context error if the context is done first.
+func sleep(ctx context.Context, duration time.Duration) error {
+	timer := time.NewTimer(duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// retryAfter returns the delay requested by a Retry-After header,
+// which is either a number of seconds or an HTTP date.
+func retryAfter(headers http.Header) (time.Duration, bool) {
+	value := headers.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+
+		return delay, true
+	}
+
+	return 0, false
+}
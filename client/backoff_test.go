@@ -0,0 +1,53 @@
+package client
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestBackoffDelay(t *testing.T) {
+	backoff := Backoff{
+		Initial:    100 * time.Millisecond,
+		Max:        time.Second,
+		Multiplier: 3,
+	}
+
+	expected := []time.Duration{
+		100 * time.Millisecond,
+		300 * time.Millisecond,
+		900 * time.Millisecond,
+		time.Second,
+	}
+	for i, delay := range expected {
+		if actual := backoff.Delay(i + 1); actual != delay {
+			t.Fatalf("Expected delay of attempt %d to be %v, but got %v", i+1, delay, actual)
+		}
+	}
+
+	if actual := (Backoff{}).Delay(1); actual != defaultInitialBackoff {
+		t.Fatalf("Expected default initial delay to be %v, but got %v", defaultInitialBackoff, actual)
+	}
+
+	jittered := Backoff{Initial: time.Second, Jitter: true}.Delay(1)
+	if jittered < 500*time.Millisecond || jittered > time.Second {
+		t.Fatalf("Expected jittered delay to be between 500ms and 1s, but got %v", jittered)
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	if _, ok := retryAfter(http.Header{}); ok {
+		t.Fatalf("Expected no delay without a Retry-After header")
+	}
+
+	delay, ok := retryAfter(http.Header{"Retry-After": []string{"3"}})
+	if !ok || delay != 3*time.Second {
+		t.Fatalf("Expected a delay of 3s, but got %v", delay)
+	}
+
+	date := time.Now().Add(time.Hour).UTC().Format(http.TimeFormat)
+	delay, ok = retryAfter(http.Header{"Retry-After": []string{date}})
+	if !ok || delay <= 59*time.Minute {
+		t.Fatalf("Expected a delay of about an hour, but got %v", delay)
+	}
+}
@@ -0,0 +1,459 @@
+package client
+
+import (
+	"bytes"
+	"container/list"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	ifNoneMatchHeader     = "If-None-Match"
+	ifModifiedSinceHeader = "If-Modified-Since"
+	diskCacheFileSuffix   = ".json"
+
+	defaultMaxCacheableBodySize = 1 << 20
+)
+
+// Cache stores responses to GET requests so they can be revalidated
+// with If-None-Match and If-Modified-Since instead of downloaded again.
+//
+// Implementations must be safe for concurrent use, and must not modify
+// the responses they are given or return.
+type Cache interface {
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, response *CachedResponse)
+	Delete(key string)
+}
+
+// CachedResponse is a response stored in a Cache.
+type CachedResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       []byte      `json:"body"`
+}
+
+// response returns a new response for the request with the cached status, headers and body.
+func (r *CachedResponse) response(request *http.Request) *http.Response {
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          ioutil.NopCloser(bytes.NewReader(r.Body)),
+		ContentLength: int64(len(r.Body)),
+		Request:       request,
+	}
+}
+
+// httpCache revalidates GET requests against the responses in a Cache.
+type httpCache struct {
+	store       Cache
+	maxBodySize int64 // Maximum bytes of the bodies of stored responses
+}
+
+// newHTTPCache returns nil if no cache is given.
+func newHTTPCache(store Cache, maxBodySize int64) *httpCache {
+	if store == nil {
+		return nil
+	}
+
+	if maxBodySize <= 0 {
+		maxBodySize = defaultMaxCacheableBodySize
+	}
+
+	return &httpCache{store: store, maxBodySize: maxBodySize}
+}
+
+// lookup returns the cache key of a request along with its cached response, if any,
+// and makes the request conditional on that response having changed. Requests that
+// are not cached, such as those that are already conditional, have an empty key.
+func (h *httpCache) lookup(request *http.Request) (string, *CachedResponse) {
+	if h == nil || request.Method != http.MethodGet || isConditional(request) || request.Header.Get("Range") != "" {
+		return "", nil
+	}
+
+	key := cacheKey(request)
+	cached, ok := h.store.Get(key)
+	if !ok {
+		return key, nil
+	}
+
+	if etag := cached.Header.Get("ETag"); etag != "" {
+		request.Header.Set(ifNoneMatchHeader, etag)
+	}
+	if lastModified := cached.Header.Get("Last-Modified"); lastModified != "" {
+		request.Header.Set(ifModifiedSinceHeader, lastModified)
+	}
+
+	return key, cached
+}
+
+// handle serves a 304 response from the cached response, and stores successful
+// responses that can be revalidated. Responses with bodies larger than the
+// maximum size are returned without being stored.
+func (h *httpCache) handle(key string, cached *CachedResponse, response *http.Response) (*http.Response, error) {
+	if h == nil || key == "" || response == nil {
+		return response, nil
+	}
+
+	if response.StatusCode == http.StatusNotModified && cached != nil {
+		_, _ = ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+
+		updated := &CachedResponse{
+			StatusCode: cached.StatusCode,
+			Header:     cached.Header.Clone(),
+			Body:       cached.Body,
+		}
+		for name, values := range response.Header {
+			updated.Header[name] = values
+		}
+		h.store.Set(key, updated)
+
+		return updated.response(response.Request), nil
+	}
+
+	if response.StatusCode != http.StatusOK || !isCacheable(response.Header) || response.ContentLength > h.maxBodySize {
+		return response, nil
+	}
+
+	body, err := ioutil.ReadAll(io.LimitReader(response.Body, h.maxBodySize+1))
+	if err != nil {
+		_ = response.Body.Close()
+		return nil, err
+	}
+
+	if int64(len(body)) > h.maxBodySize {
+		response.Body = &peekedBody{io.MultiReader(bytes.NewReader(body), response.Body), response.Body}
+		return response, nil
+	}
+
+	_ = response.Body.Close()
+	response.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	h.store.Set(key, &CachedResponse{
+		StatusCode: response.StatusCode,
+		Header:     response.Header.Clone(),
+		Body:       body,
+	})
+
+	return response, nil
+}
+
+// isConditional reports whether a request is only answered with a body if the resource changed.
+// A 304 response to such a request is returned rather than treated as an unsupported redirect.
+func isConditional(request *http.Request) bool {
+	return request.Header.Get(ifNoneMatchHeader) != "" || request.Header.Get(ifModifiedSinceHeader) != ""
+}
+
+// isCacheable reports whether a response can be revalidated and may be stored.
+func isCacheable(headers http.Header) bool {
+	if headers.Get("ETag") == "" && headers.Get("Last-Modified") == "" {
+		return false
+	}
+
+	for _, directive := range strings.Split(headers.Get("Cache-Control"), ",") {
+		if strings.EqualFold(strings.TrimSpace(directive), "no-store") {
+			return false
+		}
+	}
+
+	return true
+}
+
+// cacheKey identifies a request by its method, path, query and the subject of its token,
+// so responses are never shared between users.
+func cacheKey(request *http.Request) string {
+	return strings.Join([]string{
+		request.Method,
+		request.URL.Path,
+		request.URL.RawQuery,
+		tokenSubject(request.Header.Get(authorizationHeader)),
+	}, " ")
+}
+
+// tokenSubject returns the sub claim of a bearer JWT, or a hash of the
+// authorization header if it has none.
+func tokenSubject(authorization string) string {
+	if authorization == "" {
+		return ""
+	}
+
+	parts := strings.Split(strings.TrimPrefix(authorization, "Bearer "), ".")
+	if len(parts) == 3 {
+		payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+		if err == nil {
+			var claims struct {
+				Subject string `json:"sub"`
+			}
+			if json.Unmarshal(payload, &claims) == nil && claims.Subject != "" {
+				return "sub:" + claims.Subject
+			}
+		}
+	}
+
+	hash := sha256.Sum256([]byte(authorization))
+	return "auth:" + hex.EncodeToString(hash[:])
+}
+
+// MemoryCache is a Cache that keeps a bounded number of responses in memory,
+// evicting the least recently used ones.
+type MemoryCache struct {
+	maxEntries int
+
+	mutex   sync.Mutex
+	entries map[string]*list.Element
+	order   *list.List // Most recently used entries first
+}
+
+type memoryCacheEntry struct {
+	key      string
+	response *CachedResponse
+}
+
+// NewMemoryCache returns a MemoryCache holding up to maxEntries responses, or 1000 if not positive.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	if maxEntries <= 0 {
+		maxEntries = 1000
+	}
+
+	return &MemoryCache{
+		maxEntries: maxEntries,
+		entries:    map[string]*list.Element{},
+		order:      list.New(),
+	}
+}
+
+// Get implements the Cache interface.
+func (c *MemoryCache) Get(key string) (*CachedResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	element, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	return element.Value.(*memoryCacheEntry).response, true
+}
+
+// Set implements the Cache interface.
+func (c *MemoryCache) Set(key string, response *CachedResponse) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		element.Value.(*memoryCacheEntry).response = response
+		c.order.MoveToFront(element)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&memoryCacheEntry{key, response})
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*memoryCacheEntry).key)
+	}
+}
+
+// Delete implements the Cache interface.
+func (c *MemoryCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.entries[key]; ok {
+		c.order.Remove(element)
+		delete(c.entries, key)
+	}
+}
+
+// DiskCache is a Cache that stores responses as files in a directory, evicting
+// the least recently used ones once their total size exceeds a limit.
+type DiskCache struct {
+	dir      string
+	maxBytes int64
+
+	mutex   sync.Mutex
+	files   map[string]*list.Element
+	order   *list.List // Most recently used files first
+	size    int64      // Total size of the files
+	lastErr error
+}
+
+type diskCacheFile struct {
+	name string
+	size int64
+}
+
+// diskCacheRecord is the content of a cache file.
+type diskCacheRecord struct {
+	Key      string          `json:"key"`
+	Response *CachedResponse `json:"response"`
+}
+
+// NewDiskCache returns a DiskCache storing up to maxBytes of responses in dir,
+// which is created if needed. Files left in dir by a previous DiskCache are reused.
+func NewDiskCache(dir string, maxBytes int64) (*DiskCache, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Files are used least recently first in the order of their modification times.
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().After(infos[j].ModTime())
+	})
+
+	c := &DiskCache{
+		dir:      dir,
+		maxBytes: maxBytes,
+		files:    map[string]*list.Element{},
+		order:    list.New(),
+	}
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), diskCacheFileSuffix) {
+			continue
+		}
+
+		c.files[info.Name()] = c.order.PushBack(&diskCacheFile{info.Name(), info.Size()})
+		c.size += info.Size()
+	}
+	c.evict()
+
+	return c, nil
+}
+
+// Get implements the Cache interface.
+func (c *DiskCache) Get(key string) (*CachedResponse, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	name := diskCacheFileName(key)
+	element, ok := c.files[name]
+	if !ok {
+		return nil, false
+	}
+
+	data, err := ioutil.ReadFile(filepath.Join(c.dir, name))
+	var record diskCacheRecord
+	if err == nil {
+		err = json.Unmarshal(data, &record)
+	}
+	if err != nil || record.Key != key || record.Response == nil {
+		c.remove(element)
+		return nil, false
+	}
+	c.order.MoveToFront(element)
+
+	return record.Response, true
+}
+
+// Set implements the Cache interface. Responses larger than the size limit are not stored.
+func (c *DiskCache) Set(key string, response *CachedResponse) {
+	data, err := json.Marshal(diskCacheRecord{key, response})
+	if err != nil {
+		return
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	name := diskCacheFileName(key)
+	if element, ok := c.files[name]; ok {
+		c.remove(element)
+	}
+
+	if int64(len(data)) > c.maxBytes {
+		return
+	}
+
+	// Files are replaced atomically, so concurrent readers never see partial writes.
+	file, err := ioutil.TempFile(c.dir, "tmp-")
+	if err != nil {
+		c.lastErr = err
+		return
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(file.Name(), filepath.Join(c.dir, name))
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		c.lastErr = err
+		return
+	}
+
+	c.files[name] = c.order.PushFront(&diskCacheFile{name, int64(len(data))})
+	c.size += int64(len(data))
+	c.evict()
+}
+
+// Delete implements the Cache interface.
+func (c *DiskCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if element, ok := c.files[diskCacheFileName(key)]; ok {
+		c.remove(element)
+	}
+}
+
+// Size returns the total size of the stored responses in bytes.
+func (c *DiskCache) Size() int64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.size
+}
+
+// Err returns the last error that prevented a response from being stored, if any.
+func (c *DiskCache) Err() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.lastErr
+}
+
+// evict removes the least recently used files until the size limit is met.
+func (c *DiskCache) evict() {
+	for c.size > c.maxBytes && c.order.Len() > 0 {
+		c.remove(c.order.Back())
+	}
+}
+
+// remove deletes a file from the cache and the directory.
+func (c *DiskCache) remove(element *list.Element) {
+	file := element.Value.(*diskCacheFile)
+	c.order.Remove(element)
+	delete(c.files, file.name)
+	c.size -= file.size
+	_ = os.Remove(filepath.Join(c.dir, file.name))
+}
+
+// diskCacheFileName returns the name of the file storing the response of a key.
+func diskCacheFileName(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:]) + diskCacheFileSuffix
+}
@@ -0,0 +1,257 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+)
+
+func TestClientCache(t *testing.T) {
+	var (
+		mutex       sync.Mutex
+		version     = "1"
+		conditional []string
+	)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		conditional = append(conditional, r.Header.Get(ifNoneMatchHeader))
+
+		etag := `"` + version + `"`
+		if r.URL.Path == "/no-store" {
+			w.Header().Set("Cache-Control", "no-store")
+		}
+		w.Header().Set("ETag", etag)
+
+		if r.Header.Get(ifNoneMatchHeader) == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		if r.URL.Path == "/chunked" {
+			w.(http.Flusher).Flush()
+		}
+		w.Write([]byte(`{"version": "` + version + `", "path": "` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %+v", err)
+	}
+
+	takeConditional := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		taken := conditional
+		conditional = nil
+		return taken
+	}
+
+	newCacheClient := func(cache Cache) Client {
+		return New(Options{
+			Host: uri.Host,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Cache: cache,
+		})
+	}
+
+	get := func(t *testing.T, c Client, path string, jwt *string) (int, string) {
+		response, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: path, Jwt: jwt})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Fatalf("Failed to read body: %+v", err)
+		}
+		return response.StatusCode, string(body)
+	}
+
+	t.Run("not modified responses are served from the cache", func(t *testing.T) {
+		c := newCacheClient(NewMemoryCache(10))
+
+		_, first := get(t, c, "/resource", nil)
+		status, second := get(t, c, "/resource", nil)
+		if status != http.StatusOK || second != first {
+			t.Fatalf("Expected the cached body %s with status 200, but got %s with status %d", first, second, status)
+		}
+
+		if sent := takeConditional(); len(sent) != 2 || sent[0] != "" || sent[1] != `"1"` {
+			t.Fatalf("Expected the second request to be conditional, but got %v", sent)
+		}
+
+		mutex.Lock()
+		version = "2"
+		mutex.Unlock()
+
+		if _, body := get(t, c, "/resource", nil); body != `{"version": "2", "path": "/resource"}` {
+			t.Fatalf("Expected the updated body, but got %s", body)
+		}
+		takeConditional()
+	})
+
+	t.Run("responses are not shared between token subjects", func(t *testing.T) {
+		c := newCacheClient(NewMemoryCache(10))
+		alice := testJWT(`{"sub": "alice"}`)
+		bob := testJWT(`{"sub": "bob"}`)
+
+		get(t, c, "/users", &alice)
+		get(t, c, "/users", &bob)
+		get(t, c, "/users", &alice)
+
+		if sent := takeConditional(); len(sent) != 3 || sent[1] != "" || sent[2] == "" {
+			t.Fatalf("Expected only the repeated request of alice to be conditional, but got %v", sent)
+		}
+	})
+
+	t.Run("no-store responses are not cached", func(t *testing.T) {
+		c := newCacheClient(NewMemoryCache(10))
+
+		get(t, c, "/no-store", nil)
+		get(t, c, "/no-store", nil)
+
+		if sent := takeConditional(); len(sent) != 2 || sent[1] != "" {
+			t.Fatalf("Expected no conditional requests, but got %v", sent)
+		}
+	})
+
+	t.Run("responses larger than the maximum size are not cached", func(t *testing.T) {
+		c := New(Options{
+			Host: uri.Host,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Cache:                NewMemoryCache(10),
+			MaxCacheableBodySize: 16,
+		})
+
+		for _, path := range []string{"/large", "/chunked"} {
+			get(t, c, path, nil)
+			status, body := get(t, c, path, nil)
+			mutex.Lock()
+			expected := `{"version": "` + version + `", "path": "` + path + `"}`
+			mutex.Unlock()
+
+			if status != http.StatusOK || body != expected {
+				t.Fatalf("Expected the whole body %s, but got %s with status %d", expected, body, status)
+			}
+
+			if sent := takeConditional(); len(sent) != 2 || sent[1] != "" {
+				t.Fatalf("Expected no conditional requests for %s, but got %v", path, sent)
+			}
+		}
+	})
+
+	t.Run("conditional requests of the caller", func(t *testing.T) {
+		c := newCacheClient(nil)
+
+		response, err := c.Request(context.Background(), RequestOptions{
+			Method:  "GET",
+			Path:    "/resource",
+			Headers: &http.Header{ifNoneMatchHeader: []string{`"2"`}},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		response.Body.Close()
+
+		if response.StatusCode != http.StatusNotModified {
+			t.Fatalf("Expected status 304, but got %d", response.StatusCode)
+		}
+		takeConditional()
+	})
+}
+
+func TestMemoryCache(t *testing.T) {
+	cache := NewMemoryCache(2)
+	cache.Set("a", &CachedResponse{StatusCode: 200})
+	cache.Set("b", &CachedResponse{StatusCode: 200})
+	cache.Get("a")
+	cache.Set("c", &CachedResponse{StatusCode: 200})
+
+	if _, ok := cache.Get("b"); ok {
+		t.Fatalf("Expected the least recently used entry to be evicted")
+	}
+
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Fatalf("Expected entry %s to be cached", key)
+		}
+	}
+
+	cache.Delete("a")
+	if _, ok := cache.Get("a"); ok {
+		t.Fatalf("Expected entry a to be deleted")
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	dir := t.TempDir()
+	response := &CachedResponse{
+		StatusCode: 200,
+		Header:     http.Header{"Etag": []string{`"1"`}},
+		Body:       []byte(`{"id": 1}`),
+	}
+
+	cache, err := NewDiskCache(dir, 1000)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %+v", err)
+	}
+	cache.Set("a", response)
+	cache.Set("b", response)
+
+	reopened, err := NewDiskCache(dir, 1000)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %+v", err)
+	}
+
+	cached, ok := reopened.Get("a")
+	if !ok || string(cached.Body) != `{"id": 1}` || cached.Header.Get("ETag") != `"1"` {
+		t.Fatalf("Expected the stored response, but got %+v", cached)
+	}
+
+	if reopened.Size() != cache.Size() {
+		t.Fatalf("Expected size %d, but got %d", cache.Size(), reopened.Size())
+	}
+
+	// Only one response fits within the limit, so the least recently used one is evicted.
+	limited, err := NewDiskCache(t.TempDir(), cache.Size()/2+1)
+	if err != nil {
+		t.Fatalf("Expected no error, but got %+v", err)
+	}
+	limited.Set("a", response)
+	limited.Set("b", response)
+
+	if _, ok := limited.Get("a"); ok {
+		t.Fatalf("Expected entry a to be evicted")
+	}
+
+	if _, ok := limited.Get("b"); !ok {
+		t.Fatalf("Expected entry b to be cached")
+	}
+
+	limited.Set("large", &CachedResponse{StatusCode: 200, Body: make([]byte, 1000)})
+	if _, ok := limited.Get("large"); ok {
+		t.Fatalf("Expected responses larger than the limit not to be stored")
+	}
+}
+
+// testJWT returns an unsigned token with the given claims.
+func testJWT(claims string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(`{"alg": "none"}`)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(claims)) + "."
+}
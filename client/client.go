@@ -6,13 +6,14 @@
 package client
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"net/http"
+	"time"
+
+	"github.com/pusher/pusher-platform-go/tracing"
 )
 
 const authorizationHeader = "Authorization"
@@ -20,62 +21,286 @@ const authorizationHeader = "Authorization"
 // Client is a low level interface for clients of the elements protocol.
 type Client interface {
 	Request(ctx context.Context, options RequestOptions) (*http.Response, error)
+	Subscribe(ctx context.Context, options RequestOptions) (Subscription, error)
+	SubscribeResumable(
+		ctx context.Context,
+		options RequestOptions,
+		resumeOptions ResumeOptions,
+	) (ResumableSubscription, error)
 }
 
 // New builds a new Client.
+//
+// If the options are invalid, every request fails with the error returned by Options.Validate,
+// and likewise if the client certificate or root CAs of the TLS options fail to load.
 func New(options Options) Client {
 	return newClient(options)
 }
 
 // Request allows making HTTP calls.
+//
+// Failed requests are retried according to the retry policy of the client.
+// GET requests are revalidated against the responses in the cache of the client, if any,
+// and 304 responses to them are served from the cache. Concurrent identical GET requests
+// share a single request if the client coalesces requests.
+// Every attempt is sent with the same X-Request-ID header, which is taken from the
+// headers of the options, then from the context, and generated otherwise.
 func (c *client) Request(ctx context.Context, options RequestOptions) (*http.Response, error) {
-	request, err := buildRequest(ctx, c.schema, c.host, options)
+	if options.Timeout <= 0 {
+		return c.makeRequest(ctx, options)
+	}
+
+	// The deadline of the call must outlive Request, since the body is read afterwards.
+	ctx, cancel := context.WithTimeout(ctx, options.Timeout)
+	response, err := c.makeRequest(ctx, options)
+	if err != nil {
+		cancel()
+		return response, err
+	}
+	response.Body = &cancelOnCloseBody{response.Body, cancel}
+
+	return response, nil
+}
+
+// makeRequest makes a call with the given options.
+func (c *client) makeRequest(ctx context.Context, options RequestOptions) (*http.Response, error) {
+	call := c.newCall(c.underlyingClient, options)
+
+	var err error
+	call.idempotencyKey, err = call.retryPolicy.idempotencyKey(options)
+	if err != nil {
+		return nil, err
+	}
+
+	call.requestID, err = requestIDFor(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := c.newRequest(ctx, options)
 	if err != nil {
 		return nil, err
 	}
 
-	return sendRequest(c.underlyingClient, request, c.options.DontFollowRedirect)
+	request.Header = request.Header.Clone()
+	request.Header.Set(requestIDHeader, call.requestID)
+	if call.idempotencyKey != "" {
+		request.Header.Set(idempotencyKeyHeader, call.idempotencyKey)
+	}
+
+	if key := c.coalescer.key(ctx, request, options); key != "" {
+		return c.coalescer.do(ctx, key, func(ctx context.Context) (*http.Response, error) {
+			return c.do(ctx, request.WithContext(ctx), call)
+		})
+	}
+
+	return c.do(ctx, request, call)
+}
+
+// do sends a request, retrying it if it fails and serving it from the cache where possible.
+func (c *client) do(ctx context.Context, request *http.Request, call call) (*http.Response, error) {
+	cacheKey, cached := c.cache.lookup(request)
+
+	for attempt := 0; ; attempt++ {
+		response, err := c.sendWithFailover(ctx, request, call, attempt > 0)
+
+		retry, ok := call.retryPolicy.retry(ctx, request, attempt, err)
+		if !ok || sleep(ctx, call.retryPolicy.delay(attempt+1, err)) != nil {
+			if err == nil {
+				response, err = c.cache.handle(cacheKey, cached, response)
+			}
+			return response, withRequestIDs(err, call.requestID, call.idempotencyKey)
+		}
+		request = retry
+	}
+}
+
+// send makes a single attempt of a request, which is rate limited, traced, logged and reported to metrics.
+// Attempts that retry an earlier attempt of the call are reported as retries.
+func (c *client) send(ctx context.Context, request *http.Request, call call, retry bool) (*http.Response, error) {
+	if err := c.options.RateLimiter.wait(ctx, request.URL.Host); err != nil {
+		return nil, err
+	}
+
+	request, span := c.traceRequest(request)
+	requestBody := c.logger.captureRequestBody(request)
+	bytesOut := c.metrics.countRequestBody(request)
+	start := time.Now()
+	response, err := sendRequest(call.httpClient, request, call.dontFollowRedirect, c.maxErrorBodySize)
+	latency := time.Since(start)
+	endSpan(span, response, err)
+	c.options.RateLimiter.update(request.URL.Host, response, err)
+
+	c.metrics.observe(ctx, request, response, err, latency, bytesOut, retry)
+	responseBody := c.logger.peekResponseBody(response)
+	c.logger.logRequest(request, response, err, latency, requestBody, responseBody)
+
+	return response, err
+}
+
+// call holds the settings of a single call, some of which the request options may override.
+type call struct {
+	httpClient         http.Client
+	dontFollowRedirect bool
+	retryPolicy        RetryPolicy
+	requestID          string // Request ID sent with every attempt
+	idempotencyKey     string // Idempotency key sent with every attempt, if any
+}
+
+// newCall returns the settings of a call made with the given options and base HTTP client.
+func (c *client) newCall(httpClient http.Client, options RequestOptions) call {
+	call := call{
+		httpClient:         httpClient,
+		dontFollowRedirect: c.options.DontFollowRedirect,
+		retryPolicy:        c.retryPolicy,
+	}
+
+	if options.RetryPolicy != nil {
+		call.retryPolicy = *options.RetryPolicy
+	}
+
+	if options.DontFollowRedirect {
+		call.dontFollowRedirect = true
+		call.httpClient.CheckRedirect = useLastResponse
+	} else if options.RedirectPolicy != nil {
+		call.dontFollowRedirect = false
+		call.httpClient.CheckRedirect = options.RedirectPolicy.checkRedirect
+	}
+
+	// The timeout of the call replaces that of the client, and is enforced by its context.
+	if options.Timeout > 0 {
+		call.httpClient.Timeout = 0
+	}
+
+	return call
+}
+
+// cancelOnCloseBody cancels the context of a call once its response body is closed.
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close closes the body and cancels the context.
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// useLastResponse makes an HTTP client return redirect responses instead of following them.
+func useLastResponse(r *http.Request, via []*http.Request) error {
+	return http.ErrUseLastResponse
 }
 
 // Implements the Client interface.
 type client struct {
 	host             string
 	schema           string
+	pathPrefix       string
+	err              error // Validation error of the options, returned by every request
 	underlyingClient http.Client
+	streamingClient  http.Client
 	options          Options
+	logger           *requestLogger
+	metrics          *requestMetrics
+	tracer           tracing.Tracer
+	retryPolicy      RetryPolicy
+	failover         *failover
+	cache            *httpCache
+	coalescer        *coalescer
+	maxErrorBodySize int64
 }
 
 func newClient(options Options) *client {
 	c := new(client)
 	c.host = options.Host
-	c.schema = "https"
-
-	if options.TLSConfig != nil {
-		transport := &http.Transport{
-			Proxy:              http.ProxyFromEnvironment,
-			TLSClientConfig:    options.TLSConfig,
-			DisableCompression: true,
-		}
+	c.schema = defaultScheme
+	if options.Endpoint != nil {
+		c.host = options.Endpoint.hostPort()
+		c.schema = options.Endpoint.scheme()
+		c.pathPrefix = options.Endpoint.pathPrefix()
+	}
+	if options.Failover != nil && len(options.Failover.Endpoints) > 0 && options.Failover.Resolver == nil {
+		primary := options.Failover.Endpoints[0]
+		c.host = primary.hostPort()
+		c.schema = primary.scheme()
+		c.pathPrefix = primary.pathPrefix()
+	}
+	c.err = options.Validate()
 
-		c.underlyingClient = http.Client{
-			Transport: transport,
-			Timeout:   options.Timeout,
+	// The timeout applies whichever transport is used.
+	transport := options.Transport
+	if transport == nil {
+		builtTransport := newTransport(options.TLSConfig, options.TransportOptions)
+		if options.TLS != nil {
+			tlsConfig, err := options.TLS.config(options.TLSConfig)
+			if err != nil && c.err == nil {
+				c.err = err
+			}
+			builtTransport.TLSClientConfig = tlsConfig
 		}
+		transport = builtTransport
+	}
+	c.underlyingClient = http.Client{
+		Transport: transport,
+		Timeout:   options.Timeout,
 	}
 
 	// Control how the http client handles redirect responses.
 	// See: https://godoc.org/net/http#Client
 	// See: https://developer.mozilla.org/en-US/docs/Web/HTTP/Redirections
 	if options.DontFollowRedirect {
-		c.underlyingClient.CheckRedirect = func(r *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse
+		c.underlyingClient.CheckRedirect = useLastResponse
+	} else {
+		redirectPolicy := RedirectPolicy{}
+		if options.RedirectPolicy != nil {
+			redirectPolicy = *options.RedirectPolicy
 		}
+		c.underlyingClient.CheckRedirect = redirectPolicy.checkRedirect
+	}
+
+	// Subscriptions are long lived, so they must not be subject to the client timeout.
+	// They are also never compressed, so events are delivered as soon as they are received.
+	c.streamingClient = c.underlyingClient
+	c.streamingClient.Timeout = 0
+
+	if options.Compression != nil {
+		c.underlyingClient.Transport = newCompressionTransport(c.underlyingClient.Transport, *options.Compression)
 	}
 	c.options = options
+	c.logger = newRequestLogger(options.Logger, options.Logging)
+	c.metrics = newRequestMetrics(options.Metrics, options.MetricsLabels)
+	c.tracer = options.Tracer
+	if c.tracer == nil {
+		c.tracer = tracing.NoopTracer{}
+	}
+	if options.RetryPolicy != nil {
+		c.retryPolicy = *options.RetryPolicy
+	}
+	c.failover = newFailover(options.Failover)
+	c.cache = newHTTPCache(options.Cache, options.MaxCacheableBodySize)
+	c.maxErrorBodySize = options.MaxErrorBodySize
+	if c.maxErrorBodySize <= 0 {
+		c.maxErrorBodySize = defaultMaxErrorBodySize
+	}
+	if options.CoalesceRequests {
+		c.coalescer = newCoalescer()
+	}
 
 	return c
 }
 
+// newRequest builds a request to the endpoint of the client.
+func (c *client) newRequest(ctx context.Context, options RequestOptions) (*http.Request, error) {
+	if c.err != nil {
+		return nil, c.err
+	}
+
+	options.Path = c.pathPrefix + options.Path
+	return buildRequest(ctx, c.schema, c.host, options)
+}
+
 func buildRequest(
 	ctx context.Context,
 	schema string,
@@ -103,6 +328,10 @@ func buildRequest(
 
 	request.Header = *options.Headers
 	request = request.WithContext(ctx)
+	if options.ContentLength > 0 && request.ContentLength == 0 {
+		request.ContentLength = options.ContentLength
+	}
+	setGetBody(request, options.Body)
 	if options.QueryParams != nil {
 		request.URL.RawQuery = options.QueryParams.Encode()
 	}
@@ -114,6 +343,7 @@ func sendRequest(
 	httpClient http.Client,
 	request *http.Request,
 	dontFollowRedirect bool,
+	maxErrorBodySize int64,
 ) (*http.Response, error) {
 	response, err := httpClient.Do(request)
 	if err != nil {
@@ -125,22 +355,33 @@ func sendRequest(
 	case statusCode >= 200 && statusCode <= 299:
 		return response, nil
 	case statusCode >= 300 && statusCode <= 399:
-		if dontFollowRedirect {
+		if dontFollowRedirect || (statusCode == http.StatusNotModified && isConditional(request)) {
 			return response, nil
 		}
 		_ = response.Body.Close()
 
+		if (statusCode == http.StatusTemporaryRedirect || statusCode == http.StatusPermanentRedirect) &&
+			request.GetBody == nil && request.Body != nil && request.Body != http.NoBody {
+			return nil, fmt.Errorf(
+				"Unsupported Redirect Response: %v, the request body can not be sent again",
+				statusCode,
+			)
+		}
+
 		return nil, fmt.Errorf("Unsupported Redirect Response: %v", statusCode)
 	case statusCode >= 400 && statusCode <= 599:
-		responseBody := response.Body
-		bodyBytes, _ := ioutil.ReadAll(responseBody)
-		bodyReader := bytes.NewReader(bodyBytes)
+		bodyBytes, truncated, _ := readErrorBody(response.Body, maxErrorBodySize)
+		_ = response.Body.Close()
 
-		var info interface{}
-		err := readJSON(bodyReader, &info)
-		_ = responseBody.Close()
+		info, err := decodeErrorBody(bodyBytes)
 		if err != nil {
-			return nil, BodyNotJSONError{err, statusCode, bodyBytes}
+			return nil, BodyNotJSONError{
+				JSONDecodeError: err,
+				StatusCode:      statusCode,
+				BodyBytes:       bodyBytes,
+				ContentType:     response.Header.Get("Content-Type"),
+				Truncated:       truncated,
+			}
 		}
 
 		return nil, &ErrorResponse{
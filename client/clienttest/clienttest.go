@@ -0,0 +1,313 @@
+// Package clienttest provides transports that record requests made by a Client
+// to fixture files and replay them, so tests run deterministically without a server.
+//
+// A test records its fixture once against a real service:
+//
+//	recorder := clienttest.NewRecorder("testdata/users.json", nil)
+//	platformClient := client.New(client.Options{Host: host, Transport: recorder})
+//	...
+//	err := recorder.Save()
+//
+// and replays it from then on:
+//
+//	replayer, err := clienttest.NewReplayer("testdata/users.json")
+//	platformClient := client.New(client.Options{Host: host, Transport: replayer})
+//
+// Bodies are buffered in memory, so subscriptions are only replayed once they have ended.
+package clienttest
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"unicode/utf8"
+)
+
+const (
+	authorizationHeader = "Authorization"
+	redacted            = "[REDACTED]"
+	base64Encoding      = "base64"
+)
+
+// Fixture holds the interactions recorded by a Recorder.
+type Fixture struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a recorded request along with the response it received.
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is a request as stored in a fixture.
+type RecordedRequest struct {
+	Method       string      `json:"method"`
+	Path         string      `json:"path"`
+	Query        string      `json:"query,omitempty"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"` // `base64` for bodies that are not valid UTF-8
+}
+
+// RecordedResponse is a response as stored in a fixture.
+type RecordedResponse struct {
+	Status       int         `json:"status"`
+	Headers      http.Header `json:"headers,omitempty"`
+	Body         string      `json:"body,omitempty"`
+	BodyEncoding string      `json:"body_encoding,omitempty"` // `base64` for bodies that are not valid UTF-8
+}
+
+// UnmatchedRequestError is returned when no recorded interaction matches a request.
+type UnmatchedRequestError struct {
+	Method     string
+	Path       string
+	Query      string
+	Candidates int // Interactions recorded with the same method and path
+}
+
+// Implements the Error interface.
+func (e *UnmatchedRequestError) Error() string {
+	request := e.Method + " " + e.Path
+	if e.Query != "" {
+		request += "?" + e.Query
+	}
+
+	if e.Candidates == 0 {
+		return fmt.Sprintf("No interaction recorded for %s", request)
+	}
+
+	return fmt.Sprintf(
+		"None of the %d interactions recorded for %s %s match the query and body of the request",
+		e.Candidates,
+		e.Method,
+		e.Path,
+	)
+}
+
+// Recorder is a transport that records requests and their responses.
+//
+// The Authorization header is redacted from recorded requests.
+type Recorder struct {
+	path string
+	base http.RoundTripper
+
+	mutex   sync.Mutex
+	fixture Fixture
+}
+
+// NewRecorder returns a Recorder that sends requests with the base transport,
+// or http.DefaultTransport if nil, and saves them to the fixture at the given path.
+func NewRecorder(path string, base http.RoundTripper) *Recorder {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &Recorder{path: path, base: base}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	requestBody, err := readBody(&request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := r.base.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	responseBody, err := readBody(&response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := request.Header.Clone()
+	if headers.Get(authorizationHeader) != "" {
+		headers.Set(authorizationHeader, redacted)
+	}
+
+	recordedRequest := RecordedRequest{
+		Method:  request.Method,
+		Path:    request.URL.Path,
+		Query:   request.URL.Query().Encode(),
+		Headers: headers,
+	}
+	recordedRequest.Body, recordedRequest.BodyEncoding = encodeBody(requestBody)
+
+	recordedResponse := RecordedResponse{
+		Status:  response.StatusCode,
+		Headers: response.Header,
+	}
+	recordedResponse.Body, recordedResponse.BodyEncoding = encodeBody(responseBody)
+
+	r.mutex.Lock()
+	r.fixture.Interactions = append(r.fixture.Interactions, Interaction{recordedRequest, recordedResponse})
+	r.mutex.Unlock()
+
+	return response, nil
+}
+
+// Fixture returns the interactions recorded so far.
+func (r *Recorder) Fixture() Fixture {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return Fixture{append([]Interaction(nil), r.fixture.Interactions...)}
+}
+
+// Save writes the recorded interactions to the fixture file, creating its directory if needed.
+func (r *Recorder) Save() error {
+	data, err := json.MarshalIndent(r.Fixture(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(r.path, append(data, '\n'), 0644)
+}
+
+// Replayer is a transport that serves the responses of recorded interactions.
+//
+// Requests are matched on their method, path, query and body. Identical requests
+// are served the recorded responses in order, and the last one once they are used up.
+type Replayer struct {
+	mutex        sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// NewReplayer returns a Replayer serving the interactions of the fixture at the given path.
+func NewReplayer(path string) (*Replayer, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var fixture Fixture
+	if err := json.Unmarshal(data, &fixture); err != nil {
+		return nil, fmt.Errorf("Invalid fixture %s: %s", path, err)
+	}
+
+	return NewFixtureReplayer(fixture), nil
+}
+
+// NewFixtureReplayer returns a Replayer serving the interactions of the fixture.
+func NewFixtureReplayer(fixture Fixture) *Replayer {
+	return &Replayer{
+		interactions: fixture.Interactions,
+		used:         make([]bool, len(fixture.Interactions)),
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (r *Replayer) RoundTrip(request *http.Request) (*http.Response, error) {
+	body, err := readBody(&request.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	query := request.URL.Query().Encode()
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	candidates := 0
+	match := -1
+	for i, interaction := range r.interactions {
+		recorded := interaction.Request
+		if recorded.Method != request.Method || recorded.Path != request.URL.Path {
+			continue
+		}
+		candidates++
+
+		recordedBody, err := decodeBody(recorded.Body, recorded.BodyEncoding)
+		if err != nil || recorded.Query != query || !bytes.Equal(recordedBody, body) {
+			continue
+		}
+
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+
+	if match < 0 {
+		return nil, &UnmatchedRequestError{
+			Method:     request.Method,
+			Path:       request.URL.Path,
+			Query:      query,
+			Candidates: candidates,
+		}
+	}
+	r.used[match] = true
+
+	recorded := r.interactions[match].Response
+	responseBody, err := decodeBody(recorded.Body, recorded.BodyEncoding)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := recorded.Headers.Clone()
+	if headers == nil {
+		headers = http.Header{}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        headers,
+		Body:          ioutil.NopCloser(bytes.NewReader(responseBody)),
+		ContentLength: int64(len(responseBody)),
+		Request:       request,
+	}, nil
+}
+
+// readBody reads a body and replaces it with a reader of the same content.
+func readBody(body *io.ReadCloser) ([]byte, error) {
+	if *body == nil || *body == http.NoBody {
+		return nil, nil
+	}
+
+	data, err := ioutil.ReadAll(*body)
+	_ = (*body).Close()
+	if err != nil {
+		return nil, err
+	}
+	*body = ioutil.NopCloser(bytes.NewReader(data))
+
+	return data, nil
+}
+
+// encodeBody returns the body as a string, which is base64 encoded if it is not valid UTF-8.
+func encodeBody(body []byte) (string, string) {
+	if utf8.Valid(body) {
+		return string(body), ""
+	}
+
+	return base64.StdEncoding.EncodeToString(body), base64Encoding
+}
+
+// decodeBody reverses encodeBody.
+func decodeBody(body string, encoding string) ([]byte, error) {
+	if encoding == base64Encoding {
+		return base64.StdEncoding.DecodeString(body)
+	}
+
+	return []byte(body), nil
+}
@@ -0,0 +1,150 @@
+package clienttest
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pusher/pusher-platform-go/client"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusCreated)
+			w.Write(body)
+			return
+		}
+
+		w.Write([]byte(`[{"id": "` + r.URL.Query().Get("role") + `"}]`))
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte{0xff, 0xfe, 0x00})
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %+v", err)
+	}
+
+	jwt := "some.jwt.string"
+	requests := []client.RequestOptions{
+		{Method: http.MethodGet, Path: "/users", QueryParams: &url.Values{"role": []string{"admin"}}, Jwt: &jwt},
+		{Method: http.MethodPost, Path: "/users", Body: strings.NewReader(`{"name": "alice"}`), Jwt: &jwt},
+		{Method: http.MethodGet, Path: "/binary"},
+	}
+
+	send := func(platformClient client.Client) []string {
+		bodies := []string{}
+		for _, options := range requests {
+			if options.Body != nil {
+				options.Body = strings.NewReader(`{"name": "alice"}`)
+			}
+
+			response, err := platformClient.Request(context.Background(), options)
+			if err != nil {
+				t.Fatalf("Expected no error, but got %+v", err)
+			}
+
+			body, _ := ioutil.ReadAll(response.Body)
+			response.Body.Close()
+			bodies = append(bodies, string(body))
+		}
+
+		return bodies
+	}
+
+	path := filepath.Join(t.TempDir(), "testdata", "users.json")
+	recorder := NewRecorder(path, &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}})
+	recorded := send(client.New(client.Options{Host: uri.Host, Transport: recorder}))
+
+	if err := recorder.Save(); err != nil {
+		t.Fatalf("Expected no error saving the fixture, but got %+v", err)
+	}
+
+	fixture, _ := ioutil.ReadFile(path)
+	if strings.Contains(string(fixture), jwt) {
+		t.Fatalf("Expected the Authorization header to be redacted, but got %s", fixture)
+	}
+
+	server.Close()
+
+	replayer, err := NewReplayer(path)
+	if err != nil {
+		t.Fatalf("Expected no error loading the fixture, but got %+v", err)
+	}
+
+	platformClient := client.New(client.Options{Host: uri.Host, Transport: replayer})
+	replayed := send(platformClient)
+
+	for i := range recorded {
+		if recorded[i] != replayed[i] {
+			t.Fatalf("Expected the replayed body %q, but got %q", recorded[i], replayed[i])
+		}
+	}
+
+	t.Run("unmatched request", func(t *testing.T) {
+		_, err := platformClient.Request(context.Background(), client.RequestOptions{
+			Method:      http.MethodGet,
+			Path:        "/users",
+			QueryParams: &url.Values{"role": []string{"member"}},
+		})
+
+		var unmatched *UnmatchedRequestError
+		if !errors.As(err, &unmatched) || unmatched.Candidates != 1 {
+			t.Fatalf("Expected an unmatched request error with 1 candidate, but got %+v", err)
+		}
+
+		if !strings.Contains(err.Error(), "GET /users") {
+			t.Fatalf("Expected the error to describe the request, but got %s", err)
+		}
+	})
+
+	t.Run("unknown path", func(t *testing.T) {
+		_, err := platformClient.Request(context.Background(), client.RequestOptions{
+			Method: http.MethodDelete,
+			Path:   "/users",
+		})
+
+		var unmatched *UnmatchedRequestError
+		if !errors.As(err, &unmatched) || unmatched.Candidates != 0 {
+			t.Fatalf("Expected an unmatched request error without candidates, but got %+v", err)
+		}
+	})
+}
+
+func TestReplayerServesResponsesInOrder(t *testing.T) {
+	request := RecordedRequest{Method: http.MethodGet, Path: "/status"}
+	replayer := NewFixtureReplayer(Fixture{Interactions: []Interaction{
+		{Request: request, Response: RecordedResponse{Status: http.StatusOK, Body: "pending"}},
+		{Request: request, Response: RecordedResponse{Status: http.StatusOK, Body: "done"}},
+	}})
+
+	bodies := []string{}
+	for i := 0; i < 3; i++ {
+		request, _ := http.NewRequest(http.MethodGet, "https://localhost/status", nil)
+		response, err := replayer.RoundTrip(request)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+
+		body, _ := ioutil.ReadAll(response.Body)
+		bodies = append(bodies, string(body))
+	}
+
+	if strings.Join(bodies, ",") != "pending,done,done" {
+		t.Fatalf("Expected the responses in order, but got %v", bodies)
+	}
+}
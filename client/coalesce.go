@@ -0,0 +1,190 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// coalescer shares a single request between concurrent identical GET requests.
+//
+// The response body of the shared request is read in full, and each caller
+// is given its own copy of the response or error, which carries the request
+// ID the shared request was sent with. Requests with a request ID set by the
+// caller are only shared with requests with the same ID. The shared request is
+// only cancelled once every caller waiting for it has given up.
+type coalescer struct {
+	mutex sync.Mutex
+	calls map[string]*coalescedCall
+}
+
+// coalescedCall is a request shared between callers.
+type coalescedCall struct {
+	done     chan struct{}
+	response *http.Response
+	body     []byte
+	err      error
+
+	waiters int // Callers waiting for the request, guarded by the mutex of the coalescer
+	cancel  context.CancelFunc
+}
+
+func newCoalescer() *coalescer {
+	return &coalescer{calls: map[string]*coalescedCall{}}
+}
+
+// key returns the key that identical requests share, or an empty key
+// for requests that are not coalesced. Requests are identical if their
+// headers, other than generated request IDs and idempotency keys, and the
+// timeout, redirect and retry settings of their options are the same.
+func (c *coalescer) key(ctx context.Context, request *http.Request, options RequestOptions) string {
+	if c == nil || request.Method != http.MethodGet || (request.Body != nil && request.Body != http.NoBody) {
+		return ""
+	}
+
+	headers := request.Header.Clone()
+	headers.Del(idempotencyKeyHeader)
+	if _, ok := RequestIDFromContext(ctx); !ok && (options.Headers == nil || options.Headers.Get(requestIDHeader) == "") {
+		headers.Del(requestIDHeader)
+	}
+
+	var key strings.Builder
+	key.WriteString(cacheKey(request) + "\n")
+	_ = headers.Write(&key)
+	fmt.Fprintf(
+		&key,
+		"%t %s %+v %+v",
+		options.DontFollowRedirect,
+		options.Timeout,
+		options.RedirectPolicy,
+		options.RetryPolicy,
+	)
+
+	return key.String()
+}
+
+// do waits for the shared request with the given key, starting it with send if
+// none is in flight. The shared request does not inherit the cancellation or
+// deadline of any caller, only the values of the context of the first one.
+func (c *coalescer) do(
+	ctx context.Context,
+	key string,
+	send func(ctx context.Context) (*http.Response, error),
+) (*http.Response, error) {
+	c.mutex.Lock()
+	call, ok := c.calls[key]
+	if !ok {
+		sharedCtx, cancel := context.WithCancel(detachedContext{ctx})
+		call = &coalescedCall{done: make(chan struct{}), cancel: cancel}
+		c.calls[key] = call
+
+		go c.run(sharedCtx, key, call, send)
+	}
+	call.waiters++
+	c.mutex.Unlock()
+
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		c.leave(key, call)
+		return nil, ctx.Err()
+	}
+
+	if call.err != nil {
+		return nil, call.copyError()
+	}
+
+	return call.copyResponse(ctx), nil
+}
+
+// run sends the shared request and reads its response body.
+func (c *coalescer) run(
+	ctx context.Context,
+	key string,
+	call *coalescedCall,
+	send func(ctx context.Context) (*http.Response, error),
+) {
+	defer close(call.done)
+	defer call.cancel()
+
+	response, err := send(ctx)
+	if err == nil {
+		call.body, err = ioutil.ReadAll(response.Body)
+		_ = response.Body.Close()
+	}
+	call.response, call.err = response, err
+
+	c.mutex.Lock()
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+	c.mutex.Unlock()
+}
+
+// copyResponse returns a copy of the response of the shared request with its own body.
+func (call *coalescedCall) copyResponse(ctx context.Context) *http.Response {
+	response := *call.response
+	response.Header = call.response.Header.Clone()
+	response.Body = ioutil.NopCloser(bytes.NewReader(call.body))
+	response.ContentLength = int64(len(call.body))
+
+	if call.response.Request != nil {
+		response.Request = call.response.Request.Clone(ctx)
+	}
+
+	return &response
+}
+
+// copyError returns a copy of the error of the shared request, so callers do not share it.
+func (call *coalescedCall) copyError() error {
+	switch err := call.err.(type) {
+	case *ErrorResponse:
+		copied := *err
+		copied.Headers = err.Headers.Clone()
+		return &copied
+	case *RequestError:
+		copied := *err
+		return &copied
+	}
+
+	return call.err
+}
+
+// leave stops a caller waiting for a shared request, which is cancelled once no caller is left.
+func (c *coalescer) leave(key string, call *coalescedCall) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+
+	// New callers start a new request rather than join the cancelled one.
+	if c.calls[key] == call {
+		delete(c.calls, key)
+	}
+	call.cancel()
+}
+
+// detachedContext keeps the values of a context without its cancellation or deadline.
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
@@ -0,0 +1,242 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientCoalesceRequests(t *testing.T) {
+	var (
+		requests   int32
+		release    = make(chan struct{})
+		cancelled  = make(chan struct{}, 1)
+		mutex      sync.Mutex
+		requestIDs []string
+	)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		mutex.Lock()
+		requestIDs = append(requestIDs, r.Header.Get(requestIDHeader))
+		mutex.Unlock()
+
+		select {
+		case <-release:
+		case <-r.Context().Done():
+			cancelled <- struct{}{}
+			return
+		}
+
+		w.Header().Set(requestIDHeader, r.Header.Get(requestIDHeader))
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "not_found"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"path": "` + r.URL.Path + `"}`))
+	}))
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %+v", err)
+	}
+
+	c := newClient(Options{
+		Host: uri.Host,
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+		CoalesceRequests: true,
+	})
+
+	// waitForWaiters waits until the given number of callers share a request to the path.
+	waitForWaiters := func(t *testing.T, path string, waiters int) {
+		for start := time.Now(); time.Since(start) < 5*time.Second; time.Sleep(time.Millisecond) {
+			c.coalescer.mutex.Lock()
+			joined := false
+			for key, call := range c.coalescer.calls {
+				joined = joined || (strings.HasPrefix(key, "GET "+path+" ") && call.waiters == waiters)
+			}
+			c.coalescer.mutex.Unlock()
+
+			if joined {
+				return
+			}
+		}
+		t.Fatalf("Expected %d callers to wait for %s", waiters, path)
+	}
+
+	t.Run("identical requests share one request", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		cancelledCtx, cancel := context.WithCancel(context.Background())
+
+		var wg sync.WaitGroup
+		bodies := make([]string, 5)
+		errs := make([]error, 5)
+		for i := range bodies {
+			ctx := context.Background()
+			if i == 0 {
+				ctx = cancelledCtx
+			}
+
+			wg.Add(1)
+			go func(i int, ctx context.Context) {
+				defer wg.Done()
+
+				response, err := c.Request(ctx, RequestOptions{Method: "GET", Path: "/shared"})
+				if err != nil {
+					errs[i] = err
+					return
+				}
+				defer response.Body.Close()
+
+				body, err := ioutil.ReadAll(response.Body)
+				bodies[i], errs[i] = string(body), err
+			}(i, ctx)
+		}
+
+		waitForWaiters(t, "/shared", 5)
+		cancel()
+		waitForWaiters(t, "/shared", 4)
+		release <- struct{}{}
+		wg.Wait()
+
+		if !errors.Is(errs[0], context.Canceled) {
+			t.Fatalf("Expected the cancelled caller to fail, but got %+v", errs[0])
+		}
+
+		for i := 1; i < len(bodies); i++ {
+			if errs[i] != nil || bodies[i] != `{"path": "/shared"}` {
+				t.Fatalf("Expected caller %d to get the body, but got %q and error %+v", i, bodies[i], errs[i])
+			}
+		}
+
+		if count := atomic.LoadInt32(&requests); count != 1 {
+			t.Fatalf("Expected 1 request, but got %d", count)
+		}
+	})
+
+	t.Run("request is cancelled once every caller gives up", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		done := make(chan error, 1)
+		go func() {
+			_, err := c.Request(ctx, RequestOptions{Method: "GET", Path: "/abandoned"})
+			done <- err
+		}()
+
+		waitForWaiters(t, "/abandoned", 1)
+		cancel()
+
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Fatalf("Expected the caller to be cancelled, but got %+v", err)
+		}
+
+		select {
+		case <-cancelled:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the shared request to be cancelled")
+		}
+	})
+
+	t.Run("callers are given the request ID that was sent", func(t *testing.T) {
+		for _, path := range []string{"/shared", "/error"} {
+			mutex.Lock()
+			requestIDs = nil
+			mutex.Unlock()
+
+			var wg sync.WaitGroup
+			received := make([]string, 3)
+			errs := make([]error, 3)
+			for i := range received {
+				wg.Add(1)
+				go func(i int) {
+					defer wg.Done()
+
+					response, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: path})
+					if err != nil {
+						var platformError *PlatformError
+						errors.As(err, &platformError)
+						requestID, _ := RequestID(err)
+						received[i], errs[i] = requestID+" "+platformError.RequestID, err
+						return
+					}
+					defer response.Body.Close()
+
+					received[i] = ResponseRequestID(response) + " " + response.Request.Header.Get(requestIDHeader)
+				}(i)
+			}
+
+			waitForWaiters(t, path, 3)
+			release <- struct{}{}
+			wg.Wait()
+
+			mutex.Lock()
+			sent := requestIDs
+			mutex.Unlock()
+
+			if len(sent) != 1 {
+				t.Fatalf("Expected a single request to %s, but got %v", path, sent)
+			}
+
+			for _, requestID := range received {
+				if expected := sent[0] + " " + sent[0]; requestID != expected {
+					t.Fatalf("Expected request IDs %s for %s, but got %s", expected, path, requestID)
+				}
+			}
+
+			if errs[0] != nil && (errs[0] == errs[1] || errs[1] == errs[2]) {
+				t.Fatalf("Expected each caller to get its own error")
+			}
+		}
+	})
+
+	t.Run("requests with different headers or settings are not coalesced", func(t *testing.T) {
+		request, err := http.NewRequest(http.MethodGet, "https://example.com/users", nil)
+		if err != nil {
+			t.Fatalf("Failed to create request: %+v", err)
+		}
+		ctx := context.Background()
+		key := c.coalescer.key(ctx, request, RequestOptions{})
+
+		withRequestID := request.Clone(ctx)
+		withRequestID.Header.Set(requestIDHeader, "other")
+		if c.coalescer.key(ctx, withRequestID, RequestOptions{}) != key {
+			t.Fatalf("Expected requests with different generated request IDs to be coalesced")
+		}
+
+		withAccept := request.Clone(ctx)
+		withAccept.Header.Set("Accept", "text/csv")
+
+		for _, other := range []string{
+			c.coalescer.key(ctx, withAccept, RequestOptions{}),
+			c.coalescer.key(WithRequestID(ctx, "other"), withRequestID, RequestOptions{}),
+			c.coalescer.key(ctx, request, RequestOptions{Timeout: time.Minute}),
+			c.coalescer.key(ctx, request, RequestOptions{DontFollowRedirect: true}),
+			c.coalescer.key(ctx, request, RequestOptions{RedirectPolicy: &RedirectPolicy{MaxRedirects: 1}}),
+			c.coalescer.key(ctx, request, RequestOptions{RetryPolicy: &RetryPolicy{MaxRetries: 1}}),
+		} {
+			if other == key {
+				t.Fatalf("Expected a different key than %q", key)
+			}
+		}
+	})
+
+	t.Run("other methods are not coalesced", func(t *testing.T) {
+		if key := c.coalescer.key(context.Background(), &http.Request{Method: http.MethodPost}, RequestOptions{}); key != "" {
+			t.Fatalf("Expected POST requests not to be coalesced, but got key %q", key)
+		}
+	})
+}
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+const (
+	acceptEncodingHeader     = "Accept-Encoding"
+	contentEncodingHeader    = "Content-Encoding"
+	gzipEncoding             = "gzip"
+	defaultCompressionMinLen = 1024
+)
+
+// Decompressor wraps a reader of an encoded response body.
+type Decompressor func(r io.Reader) (io.ReadCloser, error)
+
+// Compressor wraps a writer to encode a request body.
+type Compressor func(w io.Writer) (io.WriteCloser, error)
+
+// CompressionOptions configures compression of responses and request bodies.
+//
+// Responses are negotiated with the Accept-Encoding header and decompressed
+// transparently. gzip is always supported, other encodings such as `br` or
+// `zstd` can be supported by providing their decompressors.
+// Subscriptions are never compressed.
+type CompressionOptions struct {
+	Decompressors map[string]Decompressor // Additional response encodings, keyed by content coding
+
+	// RequestEncoding is the content coding used to compress request bodies,
+	// such as `gzip`, which must be gzip or have one of the Compressors.
+	// Request bodies are not compressed if it is empty.
+	RequestEncoding string
+	RequestMinSize  int                   // Minimum size of compressed request bodies, defaults to 1024 bytes
+	Compressors     map[string]Compressor // Additional request encodings, keyed by content coding
+}
+
+// validate reports whether there is a compressor for the request encoding.
+func (o CompressionOptions) validate() error {
+	encoding := strings.ToLower(o.RequestEncoding)
+	if encoding == "" || encoding == gzipEncoding {
+		return nil
+	}
+
+	for name := range o.Compressors {
+		if strings.ToLower(name) == encoding {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("No compressor for request encoding: %s", o.RequestEncoding)
+}
+
+// compressionTransport negotiates compressed responses and
+// compresses request bodies before passing requests on.
+type compressionTransport struct {
+	base           http.RoundTripper
+	acceptEncoding string
+	decompressors  map[string]Decompressor
+	compressor     Compressor
+	encoding       string
+	minSize        int64
+}
+
+func newCompressionTransport(base http.RoundTripper, options CompressionOptions) *compressionTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	decompressors := map[string]Decompressor{
+		gzipEncoding: func(r io.Reader) (io.ReadCloser, error) {
+			return gzip.NewReader(r)
+		},
+	}
+	for encoding, decompressor := range options.Decompressors {
+		decompressors[strings.ToLower(encoding)] = decompressor
+	}
+
+	encodings := make([]string, 0, len(decompressors))
+	for encoding := range decompressors {
+		encodings = append(encodings, encoding)
+	}
+	sort.Strings(encodings)
+
+	compressors := map[string]Compressor{
+		gzipEncoding: func(w io.Writer) (io.WriteCloser, error) {
+			return gzip.NewWriter(w), nil
+		},
+	}
+	for encoding, compressor := range options.Compressors {
+		compressors[strings.ToLower(encoding)] = compressor
+	}
+
+	minSize := int64(options.RequestMinSize)
+	if minSize <= 0 {
+		minSize = defaultCompressionMinLen
+	}
+
+	encoding := strings.ToLower(options.RequestEncoding)
+
+	return &compressionTransport{
+		base:           base,
+		acceptEncoding: strings.Join(encodings, ", "),
+		decompressors:  decompressors,
+		compressor:     compressors[encoding],
+		encoding:       encoding,
+		minSize:        minSize,
+	}
+}
+
+// RoundTrip implements the http.RoundTripper interface.
+func (t *compressionTransport) RoundTrip(request *http.Request) (*http.Response, error) {
+	request = request.Clone(request.Context())
+	request.Header.Set(acceptEncodingHeader, t.acceptEncoding)
+	t.compressRequest(request)
+
+	response, err := t.base.RoundTrip(request)
+	if err != nil {
+		return nil, err
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(response.Header.Get(contentEncodingHeader)))
+	decompressor, ok := t.decompressors[encoding]
+	if !ok ||
+		response.Body == nil ||
+		response.Body == http.NoBody ||
+		request.Method == http.MethodHead ||
+		response.StatusCode == http.StatusNoContent ||
+		response.StatusCode == http.StatusNotModified {
+		return response, nil
+	}
+
+	body, err := decompressor(response.Body)
+	switch {
+	case err == io.EOF:
+		_ = response.Body.Close()
+		response.Body = http.NoBody
+	case err != nil:
+		_ = response.Body.Close()
+		return nil, err
+	default:
+		response.Body = &decompressedBody{body, response.Body}
+	}
+	response.Header.Del(contentEncodingHeader)
+	response.Header.Del("Content-Length")
+	response.ContentLength = -1
+	response.Uncompressed = true
+
+	return response, nil
+}
+
+// compressRequest compresses request bodies of a known size of at least the minimum size.
+// Bodies are compressed as they are sent, so they are not buffered in memory.
+func (t *compressionTransport) compressRequest(request *http.Request) {
+	if t.compressor == nil ||
+		request.Body == nil ||
+		request.Body == http.NoBody ||
+		request.ContentLength < t.minSize ||
+		request.Header.Get(contentEncodingHeader) != "" {
+		return
+	}
+
+	request.Body = compressBody(request.Body, t.compressor)
+	if getBody := request.GetBody; getBody != nil {
+		request.GetBody = func() (io.ReadCloser, error) {
+			body, err := getBody()
+			if err != nil {
+				return nil, err
+			}
+
+			return compressBody(body, t.compressor), nil
+		}
+	}
+
+	request.ContentLength = -1
+	request.Header.Del("Content-Length")
+	request.Header.Set(contentEncodingHeader, t.encoding)
+}
+
+// compressBody returns a reader of the compressed body, which is
+// written by a separate goroutine as the reader is consumed.
+func compressBody(body io.ReadCloser, compressor Compressor) io.ReadCloser {
+	reader, writer := io.Pipe()
+	go func() {
+		defer body.Close()
+
+		compressed, err := compressor(writer)
+		if err != nil {
+			_ = writer.CloseWithError(err)
+			return
+		}
+
+		if _, err := io.Copy(compressed, body); err != nil {
+			_ = writer.CloseWithError(err)
+			return
+		}
+
+		_ = writer.CloseWithError(compressed.Close())
+	}()
+
+	return reader
+}
+
+// decompressedBody closes both the decompressor and the underlying body.
+type decompressedBody struct {
+	io.ReadCloser
+	body io.ReadCloser
+}
+
+func (b *decompressedBody) Close() error {
+	err := b.ReadCloser.Close()
+	if bodyErr := b.body.Close(); err == nil {
+		err = bodyErr
+	}
+
+	return err
+}
@@ -0,0 +1,232 @@
+package client
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"context"
+	"crypto/tls"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestClientCompression(t *testing.T) {
+	largeBody := strings.Repeat("hello ", 1000)
+
+	var (
+		receivedAcceptEncoding  string
+		receivedContentEncoding string
+		receivedBody            string
+	)
+
+	writeEncoded := func(w http.ResponseWriter, r *http.Request, status int, body string) {
+		receivedAcceptEncoding = r.Header.Get(acceptEncodingHeader)
+		switch {
+		case strings.Contains(receivedAcceptEncoding, "deflate"):
+			w.Header().Set(contentEncodingHeader, "deflate")
+			w.WriteHeader(status)
+			writer, _ := flate.NewWriter(w, flate.BestSpeed)
+			writer.Write([]byte(body))
+			writer.Close()
+		case strings.Contains(receivedAcceptEncoding, gzipEncoding):
+			w.Header().Set(contentEncodingHeader, gzipEncoding)
+			w.WriteHeader(status)
+			writer := gzip.NewWriter(w)
+			writer.Write([]byte(body))
+			writer.Close()
+		default:
+			w.WriteHeader(status)
+			w.Write([]byte(body))
+		}
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/large", func(w http.ResponseWriter, r *http.Request) {
+		writeEncoded(w, r, http.StatusOK, largeBody)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		writeEncoded(w, r, http.StatusBadRequest, `{"error": "invalid_name"}`)
+	})
+	mux.HandleFunc("/upload", func(w http.ResponseWriter, r *http.Request) {
+		receivedContentEncoding = r.Header.Get(contentEncodingHeader)
+		var body io.Reader = r.Body
+		if receivedContentEncoding == gzipEncoding {
+			body, _ = gzip.NewReader(r.Body)
+		}
+		bodyBytes, _ := ioutil.ReadAll(body)
+		receivedBody = string(bodyBytes)
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/subscribe", func(w http.ResponseWriter, r *http.Request) {
+		receivedAcceptEncoding = r.Header.Get(acceptEncodingHeader)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[255, 200, {}, null]` + "\n"))
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server url with error: %+v", err)
+	}
+
+	newCompressionClient := func(compression *CompressionOptions) Client {
+		return New(Options{
+			Host: uri.Host,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Compression: compression,
+		})
+	}
+
+	t.Run("Responses are not compressed by default", func(t *testing.T) {
+		resp, err := newCompressionClient(nil).Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/large",
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+		defer resp.Body.Close()
+
+		if receivedAcceptEncoding != "" {
+			t.Fatalf("Expected no Accept-Encoding, but got `%s`", receivedAcceptEncoding)
+		}
+	})
+
+	t.Run("Compressed responses are decompressed", func(t *testing.T) {
+		resp, err := newCompressionClient(&CompressionOptions{}).Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/large",
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+		defer resp.Body.Close()
+
+		if receivedAcceptEncoding != gzipEncoding {
+			t.Fatalf("Expected Accept-Encoding to be gzip, but got `%s`", receivedAcceptEncoding)
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != largeBody {
+			t.Fatalf("Expected the decompressed body, but got %d bytes", len(body))
+		}
+
+		if resp.Header.Get(contentEncodingHeader) != "" || !resp.Uncompressed {
+			t.Fatalf("Expected the response to be marked as uncompressed")
+		}
+	})
+
+	t.Run("Compressed error responses are decoded", func(t *testing.T) {
+		_, err := newCompressionClient(&CompressionOptions{}).Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/error",
+		})
+
+		errorResponse, ok := err.(*ErrorResponse)
+		if !ok || errorResponse.Status != http.StatusBadRequest {
+			t.Fatalf("Expected a 400 ErrorResponse, but got %v", err)
+		}
+	})
+
+	t.Run("Additional encodings can be negotiated", func(t *testing.T) {
+		resp, err := newCompressionClient(&CompressionOptions{
+			Decompressors: map[string]Decompressor{
+				"deflate": func(r io.Reader) (io.ReadCloser, error) {
+					return flate.NewReader(r), nil
+				},
+			},
+		}).Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/large",
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+		defer resp.Body.Close()
+
+		if receivedAcceptEncoding != "deflate, gzip" {
+			t.Fatalf("Expected Accept-Encoding to be `deflate, gzip`, but got `%s`", receivedAcceptEncoding)
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != largeBody {
+			t.Fatalf("Expected the decompressed body, but got %d bytes", len(body))
+		}
+	})
+
+	t.Run("Large request bodies are compressed", func(t *testing.T) {
+		client := newCompressionClient(&CompressionOptions{
+			RequestEncoding: gzipEncoding,
+		})
+
+		_, err := client.Request(context.Background(), RequestOptions{
+			Method: http.MethodPost,
+			Path:   "/upload",
+			Body:   bytes.NewReader([]byte(largeBody)),
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		if receivedContentEncoding != gzipEncoding || receivedBody != largeBody {
+			t.Fatalf("Expected a gzip encoded body, but got `%s` encoding", receivedContentEncoding)
+		}
+
+		_, err = client.Request(context.Background(), RequestOptions{
+			Method: http.MethodPost,
+			Path:   "/upload",
+			Body:   strings.NewReader("small"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		if receivedContentEncoding != "" || receivedBody != "small" {
+			t.Fatalf("Expected small bodies to be sent uncompressed, but got `%s` encoding", receivedContentEncoding)
+		}
+	})
+
+	t.Run("Request encodings without a compressor are rejected", func(t *testing.T) {
+		_, err := newCompressionClient(&CompressionOptions{RequestEncoding: "br"}).Request(context.Background(), RequestOptions{
+			Method: http.MethodPost,
+			Path:   "/upload",
+			Body:   bytes.NewReader([]byte(largeBody)),
+		})
+		if err == nil || !strings.Contains(err.Error(), "No compressor for request encoding: br") {
+			t.Fatalf("Expected an error for the br request encoding, but got %+v", err)
+		}
+
+		options := Options{Compression: &CompressionOptions{
+			RequestEncoding: "BR",
+			Compressors: map[string]Compressor{"br": func(w io.Writer) (io.WriteCloser, error) {
+				return nil, nil
+			}},
+		}}
+		if err := options.Validate(); err != nil {
+			t.Fatalf("Expected no error with a br compressor, but got %+v", err)
+		}
+	})
+
+	t.Run("Subscriptions are not compressed", func(t *testing.T) {
+		subscription, err := newCompressionClient(&CompressionOptions{}).Subscribe(context.Background(), RequestOptions{
+			Path: "/subscribe",
+		})
+		if err != nil {
+			t.Fatalf("Failed to subscribe with error: %+v", err)
+		}
+		<-subscription.Errors()
+
+		if receivedAcceptEncoding != "" {
+			t.Fatalf("Expected no Accept-Encoding for subscriptions, but got `%s`", receivedAcceptEncoding)
+		}
+	})
+}
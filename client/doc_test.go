@@ -32,6 +32,30 @@ func ExampleNew_request() {
 	}
 }
 
+func ExampleNew_subscribe() {
+	httpClient := client.New(client.Options{
+		Host: "mycoolhost.io",
+	})
+
+	ctx := context.Background()
+	subscription, err := httpClient.Subscribe(ctx, client.RequestOptions{
+		Path: "/foo/bar",
+	})
+	if err != nil {
+		// Do something with error
+	}
+	defer subscription.Close()
+
+	for event := range subscription.Events() {
+		// Do something with event
+		_ = event
+	}
+
+	if err := <-subscription.Errors(); err != nil {
+		// Do something with the reason the subscription ended
+	}
+}
+
 func ExampleNew_instance() {
 	// Instance with client passed in
 	// This will override the client that is constructed when creating an instance
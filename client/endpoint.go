@@ -0,0 +1,158 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// ClusterPlaceholder is replaced by the cluster of the instance locator
+// in the host of an Endpoint used by an Instance.
+const ClusterPlaceholder = "{cluster}"
+
+// clusterPlaceholderHost stands in for the placeholder while parsing base URLs,
+// since braces are not allowed in URL hosts.
+const clusterPlaceholderHost = "cluster-placeholder"
+
+const defaultScheme = "https"
+
+// Endpoint configures where the requests of a client are sent, such as
+// a local emulator over plain HTTP or a self-hosted platform deployment.
+type Endpoint struct {
+	Scheme     string // Scheme of requests, either https or http, defaults to https
+	Host       string // Host of requests, which may contain a {cluster} placeholder when used by an Instance
+	Port       int    // Optional port of requests
+	PathPrefix string // Optional prefix of the paths of requests, such as /platform
+}
+
+// ParseEndpoint parses a base URL of the format <scheme>://<host>[:<port>][/<path-prefix>].
+// The host may contain a {cluster} placeholder.
+func ParseEndpoint(baseURL string) (Endpoint, error) {
+	uri, err := url.Parse(strings.ReplaceAll(baseURL, ClusterPlaceholder, clusterPlaceholderHost))
+	if err != nil {
+		return Endpoint{}, fmt.Errorf("Invalid base URL: %s", err)
+	}
+
+	if uri.Scheme == "" || uri.Host == "" {
+		return Endpoint{}, errors.New("Base URL must be of the format <scheme>://<host>[:<port>][/<path-prefix>]")
+	}
+
+	if uri.User != nil || uri.RawQuery != "" || uri.Fragment != "" {
+		return Endpoint{}, errors.New("Base URL must not contain credentials, a query or a fragment")
+	}
+
+	endpoint := Endpoint{
+		Scheme:     uri.Scheme,
+		Host:       strings.ReplaceAll(uri.Hostname(), clusterPlaceholderHost, ClusterPlaceholder),
+		PathPrefix: uri.Path,
+	}
+
+	if port := uri.Port(); port != "" {
+		endpoint.Port, err = strconv.Atoi(port)
+		if err != nil {
+			return Endpoint{}, fmt.Errorf("Invalid base URL port: %s", port)
+		}
+	}
+
+	return endpoint, endpoint.Validate()
+}
+
+// Validate reports whether the endpoint is valid.
+func (e Endpoint) Validate() error {
+	switch e.Scheme {
+	case "", "https", "http":
+	default:
+		return fmt.Errorf("Unsupported endpoint scheme: %s", e.Scheme)
+	}
+
+	if e.Host == "" {
+		return errors.New("No endpoint host provided")
+	}
+
+	if strings.ContainsAny(e.Host, "/?#@") {
+		return fmt.Errorf("Endpoint host must not contain a scheme or a path: %s", e.Host)
+	}
+
+	if e.Port < 0 || e.Port > 65535 {
+		return fmt.Errorf("Invalid endpoint port: %d", e.Port)
+	}
+
+	if _, _, err := net.SplitHostPort(e.Host); err == nil && e.Port != 0 {
+		return fmt.Errorf("Endpoint port is set in both the host and the port: %s", e.Host)
+	}
+
+	if e.PathPrefix != "" && !strings.HasPrefix(e.PathPrefix, "/") {
+		return fmt.Errorf("Endpoint path prefix must start with a slash: %s", e.PathPrefix)
+	}
+
+	return nil
+}
+
+// WithCluster returns the endpoint with the {cluster} placeholder of its host replaced.
+func (e Endpoint) WithCluster(cluster string) Endpoint {
+	e.Host = strings.ReplaceAll(e.Host, ClusterPlaceholder, cluster)
+	return e
+}
+
+// scheme returns the scheme of requests.
+func (e Endpoint) scheme() string {
+	if e.Scheme == "" {
+		return defaultScheme
+	}
+
+	return e.Scheme
+}
+
+// hostPort returns the host of requests, including the port if one is set.
+func (e Endpoint) hostPort() string {
+	if e.Port == 0 {
+		return e.Host
+	}
+
+	return net.JoinHostPort(strings.Trim(e.Host, "[]"), strconv.Itoa(e.Port))
+}
+
+// pathPrefix returns the prefix of the paths of requests, without a trailing slash.
+func (e Endpoint) pathPrefix() string {
+	return strings.TrimRight(e.PathPrefix, "/")
+}
+
+// Validate reports whether the options are valid.
+//
+// Clients built with invalid options fail every request with the validation error.
+func (o Options) Validate() error {
+	if o.Failover != nil {
+		if err := o.Failover.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.Compression != nil {
+		if err := o.Compression.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.Transport == nil && o.TransportOptions.HTTP2 != nil {
+		if err := o.TransportOptions.HTTP2.validate(); err != nil {
+			return err
+		}
+	}
+
+	if o.Endpoint == nil {
+		return nil
+	}
+
+	if err := o.Endpoint.Validate(); err != nil {
+		return err
+	}
+
+	if strings.Contains(o.Endpoint.Host, ClusterPlaceholder) {
+		return fmt.Errorf("Endpoint host has an unresolved %s placeholder: %s", ClusterPlaceholder, o.Endpoint.Host)
+	}
+
+	return nil
+}
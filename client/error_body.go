@@ -0,0 +1,94 @@
+package client
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	defaultMaxErrorBodySize = 64 << 10
+	errorBodyPreviewSize    = 256
+)
+
+// readErrorBody reads up to maxSize bytes of an error response body,
+// reporting whether the rest of the body was discarded.
+func readErrorBody(body io.Reader, maxSize int64) ([]byte, bool, error) {
+	bodyBytes, err := ioutil.ReadAll(io.LimitReader(body, maxSize+1))
+	if int64(len(bodyBytes)) > maxSize {
+		return bodyBytes[:maxSize], true, err
+	}
+
+	return bodyBytes, false, err
+}
+
+// decodeErrorBody decodes an error response body as JSON, which includes problem
+// details. Bodies are decoded whatever their content type, since some services
+// send JSON errors as plain text.
+func decodeErrorBody(body []byte) (interface{}, error) {
+	var info interface{}
+	if err := readJSON(bytes.NewReader(body), &info); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// bodyPreview returns a readable preview of a body, which is truncated and has its
+// whitespace collapsed. Bodies whose content type is not text are summarised.
+func bodyPreview(body []byte, contentType string, truncated bool) string {
+	if len(body) == 0 {
+		return "<empty>"
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	preview := body
+	if len(preview) > errorBodyPreviewSize {
+		preview, truncated = preview[:errorBodyPreviewSize], true
+
+		// Avoid cutting the last character of text in half.
+		for i := 0; i < utf8.UTFMax-1 && !utf8.Valid(preview); i++ {
+			preview = preview[:len(preview)-1]
+		}
+	}
+
+	if !isTextMediaType(mediaType) || !utf8.Valid(preview) || bytes.IndexFunc(preview, isBinaryRune) >= 0 {
+		if mediaType == "" {
+			mediaType = "binary data"
+		}
+
+		size := fmt.Sprintf("%d", len(body))
+		if truncated {
+			size = "more than " + size
+		}
+		return fmt.Sprintf("<%s bytes of %s>", size, mediaType)
+	}
+
+	text := strings.Join(strings.Fields(string(preview)), " ")
+	if truncated {
+		text += "..."
+	}
+
+	return text
+}
+
+// isTextMediaType reports whether bodies of a media type are readable text.
+// Bodies without a content type are assumed to be text.
+func isTextMediaType(mediaType string) bool {
+	return mediaType == "" ||
+		strings.HasPrefix(mediaType, "text/") ||
+		mediaType == "application/json" ||
+		strings.HasSuffix(mediaType, "+json") ||
+		mediaType == "application/xml" ||
+		strings.HasSuffix(mediaType, "+xml")
+}
+
+// isBinaryRune reports whether a rune is a control character other than whitespace.
+func isBinaryRune(r rune) bool {
+	return unicode.IsControl(r) && !unicode.IsSpace(r)
+}
@@ -0,0 +1,140 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestClientErrorBodies(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/html", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("<html>\n  <body>Bad Gateway</body>\n"))
+		w.Write([]byte(strings.Repeat("<p>padding</p>", 100000)))
+	})
+	mux.HandleFunc("/text", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("upstream connect error\n"))
+	})
+	mux.HandleFunc("/binary", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte{0x00, 0x01, 0x02})
+	})
+	mux.HandleFunc("/problem", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"type": "https://example.com/forbidden", "title": "forbidden", "detail": "Not allowed"}`))
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %+v", err)
+	}
+
+	c := New(Options{
+		Host: uri.Host,
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+		MaxErrorBodySize: 1024,
+	})
+
+	t.Run("large bodies are truncated", func(t *testing.T) {
+		_, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/html"})
+
+		var bodyNotJSONError BodyNotJSONError
+		if !errors.As(err, &bodyNotJSONError) {
+			t.Fatalf("Expected a body not JSON error, but got %+v", err)
+		}
+
+		if !bodyNotJSONError.Truncated || len(bodyNotJSONError.BodyBytes) != 1024 {
+			t.Fatalf(
+				"Expected the body to be truncated to 1024 bytes, but got %d bytes, truncated %t",
+				len(bodyNotJSONError.BodyBytes),
+				bodyNotJSONError.Truncated,
+			)
+		}
+
+		message := err.Error()
+		if !strings.HasPrefix(message, "Body is not valid JSON. Status: 502 Body: <html> <body>Bad Gateway</body> <p>padding</p>") {
+			t.Fatalf("Expected a readable preview of the body, but got %s", message)
+		}
+
+		if !strings.Contains(message, "...") || len(message) > 400 {
+			t.Fatalf("Expected a truncated preview of the body, but got %s", message)
+		}
+	})
+
+	t.Run("text bodies are previewed", func(t *testing.T) {
+		_, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/text"})
+
+		var bodyNotJSONError BodyNotJSONError
+		if !errors.As(err, &bodyNotJSONError) {
+			t.Fatalf("Expected a body not JSON error, but got %+v", err)
+		}
+
+		if !strings.Contains(err.Error(), "Body: upstream connect error Error:") {
+			t.Fatalf("Expected a preview of the text, but got %s", err)
+		}
+
+		if !errors.Is(err, ErrServiceUnavailable) {
+			t.Fatalf("Expected a service unavailable error, but got %+v", err)
+		}
+	})
+
+	t.Run("binary bodies are summarised", func(t *testing.T) {
+		_, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/binary"})
+		if err == nil || !strings.Contains(err.Error(), "Body: <3 bytes of application/octet-stream>") {
+			t.Fatalf("Expected a summary of the body, but got %+v", err)
+		}
+	})
+
+	t.Run("problem details", func(t *testing.T) {
+		_, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/problem"})
+
+		var platformError *PlatformError
+		if !errors.As(err, &platformError) {
+			t.Fatalf("Expected a platform error, but got %+v", err)
+		}
+
+		if platformError.ErrorType != "forbidden" ||
+			platformError.ErrorDescription != "Not allowed" ||
+			platformError.ErrorURI != "https://example.com/forbidden" {
+			t.Fatalf("Expected the problem details to be decoded, but got %+v", platformError)
+		}
+	})
+}
+
+func TestBodyPreview(t *testing.T) {
+	tests := []struct {
+		body        string
+		contentType string
+		truncated   bool
+		expected    string
+	}{
+		{"", "", false, "<empty>"},
+		{"Service\n  Unavailable", "text/plain; charset=utf-8", false, "Service Unavailable"},
+		{"Service Unavailable", "", true, "Service Unavailable..."},
+		{strings.Repeat("é", 200), "text/plain", false, strings.Repeat("é", 128) + "..."},
+		{"\x00\x01", "", false, "<2 bytes of binary data>"},
+		{"PK\x03\x04", "application/zip", true, "<more than 4 bytes of application/zip>"},
+	}
+
+	for _, test := range tests {
+		if preview := bodyPreview([]byte(test.body), test.contentType, test.truncated); preview != test.expected {
+			t.Fatalf("Expected preview %q, but got %q", test.expected, preview)
+		}
+	}
+}
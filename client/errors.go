@@ -0,0 +1,145 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const requestIDHeader = "X-Request-ID"
+
+// Sentinel error classes that platform errors can be compared against with errors.Is.
+var (
+	ErrBadRequest          = errors.New("bad request")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrForbidden           = errors.New("forbidden")
+	ErrNotFound            = errors.New("not found")
+	ErrConflict            = errors.New("conflict")
+	ErrUnprocessableEntity = errors.New("unprocessable entity")
+	ErrRateLimited         = errors.New("rate limited")
+	ErrServerError         = errors.New("server error")
+	ErrServiceUnavailable  = errors.New("service unavailable")
+)
+
+// PlatformError is the typed representation of an error returned by the platform.
+//
+// Errors returned by a Client wrap a PlatformError, which can be retrieved with
+// errors.As and compared against the sentinel error classes with errors.Is.
+type PlatformError struct {
+	Status           int         // HTTP status code of the response
+	Headers          http.Header // Headers of the response
+	RequestID        string      // Request ID reported by the platform, or otherwise the one the request was sent with
+	ErrorType        string      // The `error` field of the platform error
+	ErrorDescription string      // The `error_description` field of the platform error
+	ErrorURI         string      // The `error_uri` field of the platform error
+	Info             interface{} // The raw decoded error body
+}
+
+// newPlatformError builds a PlatformError from the status, headers and decoded body of a
+// response, and the request ID the request was sent with.
+func newPlatformError(status int, headers http.Header, info interface{}, requestID string) *PlatformError {
+	platformError := &PlatformError{
+		Status:    status,
+		Headers:   headers,
+		Info:      info,
+		RequestID: requestID,
+	}
+
+	if headers != nil && headers.Get(requestIDHeader) != "" {
+		platformError.RequestID = headers.Get(requestIDHeader)
+	}
+
+	if fields, ok := info.(map[string]interface{}); ok {
+		platformError.ErrorType, _ = fields["error"].(string)
+		platformError.ErrorDescription, _ = fields["error_description"].(string)
+		platformError.ErrorURI, _ = fields["error_uri"].(string)
+
+		// Problem details (RFC 7807) sent by proxies use different field names.
+		if platformError.ErrorType == "" {
+			platformError.ErrorType, _ = fields["title"].(string)
+		}
+		if platformError.ErrorDescription == "" {
+			platformError.ErrorDescription, _ = fields["detail"].(string)
+		}
+		if platformError.ErrorURI == "" {
+			platformError.ErrorURI, _ = fields["type"].(string)
+		}
+	}
+
+	return platformError
+}
+
+// Implements the Error interface.
+func (e *PlatformError) Error() string {
+	message := fmt.Sprintf("Platform error: %d", e.Status)
+	if e.ErrorType != "" {
+		message = fmt.Sprintf("%s %s", message, e.ErrorType)
+	}
+
+	if e.ErrorDescription != "" {
+		message = fmt.Sprintf("%s: %s", message, e.ErrorDescription)
+	}
+
+	if e.RequestID != "" {
+		message = fmt.Sprintf("%s (request ID: %s)", message, e.RequestID)
+	}
+
+	return message
+}
+
+// Is reports whether the error belongs to the given sentinel error class.
+func (e *PlatformError) Is(target error) bool {
+	return statusIs(e.Status, target)
+}
+
+// Retryable reports whether the request may succeed if it is made again.
+func (e *PlatformError) Retryable() bool {
+	return isRetryableStatus(e.Status)
+}
+
+// Temporary reports whether the error is caused by a transient condition,
+// such as rate limiting or the service being unavailable.
+func (e *PlatformError) Temporary() bool {
+	switch e.Status {
+	case http.StatusRequestTimeout,
+		http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+
+	return false
+}
+
+// RetryAfter returns the delay requested by the Retry-After header, if any.
+func (e *PlatformError) RetryAfter() (time.Duration, bool) {
+	return retryAfter(e.Headers)
+}
+
+// statusIs reports whether a status code belongs to the given sentinel error class.
+func statusIs(status int, target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return status == http.StatusBadRequest
+	case ErrUnauthorized:
+		return status == http.StatusUnauthorized
+	case ErrForbidden:
+		return status == http.StatusForbidden
+	case ErrNotFound:
+		return status == http.StatusNotFound
+	case ErrConflict:
+		return status == http.StatusConflict
+	case ErrUnprocessableEntity:
+		return status == http.StatusUnprocessableEntity
+	case ErrRateLimited:
+		return status == http.StatusTooManyRequests
+	case ErrServerError:
+		return status >= 500 && status <= 599
+	case ErrServiceUnavailable:
+		return status == http.StatusServiceUnavailable
+	}
+
+	return false
+}
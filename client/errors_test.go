@@ -0,0 +1,112 @@
+package client
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestPlatformError(t *testing.T) {
+	err := error(&ErrorResponse{
+		Status: http.StatusNotFound,
+		Headers: http.Header{
+			"X-Request-Id": []string{"request-id"},
+		},
+		Info: map[string]interface{}{
+			"error":             "services/not_found",
+			"error_description": "The resource could not be found",
+			"error_uri":         "https://docs.pusher.com/errors/not_found",
+		},
+	})
+
+	var platformError *PlatformError
+	if !errors.As(err, &platformError) {
+		t.Fatalf("Expected error to wrap a PlatformError")
+	}
+
+	if platformError.ErrorType != "services/not_found" {
+		t.Fatalf("Expected error type `services/not_found`, but got `%s`", platformError.ErrorType)
+	}
+
+	if platformError.ErrorDescription != "The resource could not be found" {
+		t.Fatalf("Unexpected error description `%s`", platformError.ErrorDescription)
+	}
+
+	if platformError.ErrorURI != "https://docs.pusher.com/errors/not_found" {
+		t.Fatalf("Unexpected error URI `%s`", platformError.ErrorURI)
+	}
+
+	if platformError.RequestID != "request-id" {
+		t.Fatalf("Expected request ID `request-id`, but got `%s`", platformError.RequestID)
+	}
+
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Expected error to be ErrNotFound")
+	}
+
+	if errors.Is(err, ErrConflict) || errors.Is(err, ErrServerError) {
+		t.Fatalf("Expected error to only match ErrNotFound")
+	}
+
+	if platformError.Retryable() || platformError.Temporary() {
+		t.Fatalf("Expected a 404 to be neither retryable nor temporary")
+	}
+}
+
+func TestPlatformErrorClasses(t *testing.T) {
+	testCases := []struct {
+		status    int
+		class     error
+		retryable bool
+		temporary bool
+	}{
+		{http.StatusBadRequest, ErrBadRequest, false, false},
+		{http.StatusUnauthorized, ErrUnauthorized, false, false},
+		{http.StatusForbidden, ErrForbidden, false, false},
+		{http.StatusConflict, ErrConflict, false, false},
+		{http.StatusUnprocessableEntity, ErrUnprocessableEntity, false, false},
+		{http.StatusTooManyRequests, ErrRateLimited, true, true},
+		{http.StatusInternalServerError, ErrServerError, true, false},
+		{http.StatusServiceUnavailable, ErrServiceUnavailable, true, true},
+		{http.StatusServiceUnavailable, ErrServerError, true, true},
+	}
+
+	for _, testCase := range testCases {
+		platformError := newPlatformError(testCase.status, nil, nil, "")
+		if !errors.Is(platformError, testCase.class) {
+			t.Fatalf("Expected status %d to be %v", testCase.status, testCase.class)
+		}
+
+		if platformError.Retryable() != testCase.retryable {
+			t.Fatalf("Expected status %d retryable to be %v", testCase.status, testCase.retryable)
+		}
+
+		if platformError.Temporary() != testCase.temporary {
+			t.Fatalf("Expected status %d temporary to be %v", testCase.status, testCase.temporary)
+		}
+	}
+}
+
+func TestPlatformErrorFromOtherErrors(t *testing.T) {
+	if err := error(BodyNotJSONError{StatusCode: http.StatusBadGateway}); !errors.Is(err, ErrServerError) {
+		t.Fatalf("Expected BodyNotJSONError with status 502 to be ErrServerError")
+	}
+
+	eos := &EOS{
+		Status:  http.StatusTooManyRequests,
+		Headers: http.Header{"Retry-After": []string{"2"}},
+	}
+	if !errors.Is(eos, ErrRateLimited) {
+		t.Fatalf("Expected EOS with status 429 to be ErrRateLimited")
+	}
+
+	var platformError *PlatformError
+	if !errors.As(eos, &platformError) {
+		t.Fatalf("Expected EOS to wrap a PlatformError")
+	}
+
+	if delay, ok := platformError.RetryAfter(); !ok || delay != 2*time.Second {
+		t.Fatalf("Expected a retry after delay of 2s, but got %v", delay)
+	}
+}
@@ -0,0 +1,245 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultFailoverCooldown = 30 * time.Second
+
+// FailoverOptions configures sending requests to several endpoints, such as
+// the hosts of several clusters, in order of preference.
+//
+// Endpoints are ejected for a cool-down when requests to them fail without
+// a response, such as on DNS or connection errors. Requests are sent to the
+// first endpoint that is not ejected, and idempotent requests, or requests
+// with an idempotency key, are sent again to the next endpoint straight away.
+// Ejected endpoints are only used once every endpoint has been ejected.
+type FailoverOptions struct {
+	Endpoints []Endpoint    // Endpoints in order of preference
+	Cooldown  time.Duration // Time failed endpoints are ejected for, defaults to 30s
+
+	// Resolver optionally returns the endpoints in order of preference for each request.
+	// Endpoints is ignored if it is set.
+	Resolver func(ctx context.Context) ([]Endpoint, error)
+}
+
+// failover tracks the health of the endpoints of a client.
+type failover struct {
+	options FailoverOptions
+
+	mutex   sync.Mutex
+	ejected map[string]time.Time // Time until which endpoints are ejected, keyed by endpointKey
+}
+
+// newFailover returns nil if no failover options are given.
+func newFailover(options *FailoverOptions) *failover {
+	if options == nil {
+		return nil
+	}
+
+	f := &failover{options: *options, ejected: map[string]time.Time{}}
+	if f.options.Cooldown <= 0 {
+		f.options.Cooldown = defaultFailoverCooldown
+	}
+
+	return f
+}
+
+// validate reports whether the failover options are valid.
+func (o FailoverOptions) validate() error {
+	if o.Resolver != nil {
+		return nil
+	}
+
+	if len(o.Endpoints) == 0 {
+		return errors.New("No failover endpoints provided")
+	}
+
+	return validateEndpoints(o.Endpoints)
+}
+
+// validateEndpoints reports whether every endpoint is valid and fully resolved.
+func validateEndpoints(endpoints []Endpoint) error {
+	for _, endpoint := range endpoints {
+		if err := endpoint.Validate(); err != nil {
+			return err
+		}
+
+		if strings.Contains(endpoint.Host, ClusterPlaceholder) {
+			return fmt.Errorf("Endpoint host has an unresolved %s placeholder: %s", ClusterPlaceholder, endpoint.Host)
+		}
+	}
+
+	return nil
+}
+
+// endpoints returns the endpoints to try in order. Ejected endpoints come
+// last, ordered by the time their ejection ends.
+func (f *failover) endpoints(ctx context.Context) ([]Endpoint, error) {
+	endpoints := f.options.Endpoints
+	if f.options.Resolver != nil {
+		resolved, err := f.options.Resolver(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(resolved) == 0 {
+			return nil, errors.New("No endpoints resolved")
+		}
+
+		if err := validateEndpoints(resolved); err != nil {
+			return nil, err
+		}
+		endpoints = resolved
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	now := time.Now()
+	healthy := make([]Endpoint, 0, len(endpoints))
+	ejected := []Endpoint{}
+	for _, endpoint := range endpoints {
+		if until, ok := f.ejected[endpointKey(endpoint)]; ok && now.Before(until) {
+			ejected = append(ejected, endpoint)
+		} else {
+			healthy = append(healthy, endpoint)
+		}
+	}
+
+	sort.SliceStable(ejected, func(i, j int) bool {
+		return f.ejected[endpointKey(ejected[i])].Before(f.ejected[endpointKey(ejected[j])])
+	})
+
+	return append(healthy, ejected...), nil
+}
+
+// report ejects the endpoint if the request to it failed without a response,
+// and restores it otherwise. It does nothing if the endpoint is nil.
+func (f *failover) report(ctx context.Context, endpoint *Endpoint, err error) {
+	if endpoint == nil {
+		return
+	}
+
+	if isHostFailure(ctx, err) {
+		f.eject(*endpoint)
+	} else {
+		f.restore(*endpoint)
+	}
+}
+
+// eject stops using an endpoint until the cool-down has passed.
+func (f *failover) eject(endpoint Endpoint) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	f.ejected[endpointKey(endpoint)] = time.Now().Add(f.options.Cooldown)
+}
+
+// restore marks an endpoint as healthy after it served a request.
+func (f *failover) restore(endpoint Endpoint) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	delete(f.ejected, endpointKey(endpoint))
+}
+
+// sendWithFailover sends the request to the endpoints of the client in order,
+// moving on to the next endpoint when an endpoint fails and the request can be sent again.
+func (c *client) sendWithFailover(
+	ctx context.Context,
+	request *http.Request,
+	call call,
+	retry bool,
+) (*http.Response, error) {
+	if c.failover == nil {
+		return c.send(ctx, request, call, retry)
+	}
+
+	endpoints, err := c.failover.endpoints(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	path := strings.TrimPrefix(request.URL.Path, c.pathPrefix)
+
+	var response *http.Response
+	for i, endpoint := range endpoints {
+		target, ok := retarget(request, endpoint, path, i > 0)
+		if !ok {
+			break
+		}
+
+		// Moving on to another endpoint is not a retry of the call.
+		response, err = c.send(ctx, target, call, retry && i == 0)
+		c.failover.report(ctx, &endpoints[i], err)
+		if !isHostFailure(ctx, err) {
+			return response, err
+		}
+
+		if !isIdempotentMethod(request.Method) && request.Header.Get(idempotencyKeyHeader) == "" {
+			break
+		}
+	}
+
+	return response, err
+}
+
+// firstEndpoint returns the request sent to the preferred endpoint of the client,
+// along with that endpoint, which is nil unless failover is enabled.
+func (c *client) firstEndpoint(ctx context.Context, request *http.Request) (*http.Request, *Endpoint, error) {
+	if c.failover == nil {
+		return request, nil, nil
+	}
+
+	endpoints, err := c.failover.endpoints(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	target, _ := retarget(request, endpoints[0], strings.TrimPrefix(request.URL.Path, c.pathPrefix), false)
+	return target, &endpoints[0], nil
+}
+
+// retarget returns a copy of the request sent to the given endpoint.
+// The body is rewound if required, which fails if the body can not be sent again.
+func retarget(request *http.Request, endpoint Endpoint, path string, rewind bool) (*http.Request, bool) {
+	target := request.Clone(request.Context())
+	target.URL.Scheme = endpoint.scheme()
+	target.URL.Host = endpoint.hostPort()
+	target.URL.Path = endpoint.pathPrefix() + path
+	target.URL.RawPath = ""
+	target.Host = target.URL.Host
+
+	if rewind && request.Body != nil && request.Body != http.NoBody {
+		if request.GetBody == nil {
+			return nil, false
+		}
+
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, false
+		}
+		target.Body = body
+	}
+
+	return target, true
+}
+
+// isHostFailure reports whether a request failed because of a network error reaching
+// the host, rather than because its context is done or the host sent a response.
+func isHostFailure(ctx context.Context, err error) bool {
+	return err != nil && ctx.Err() == nil && isNetworkError(err)
+}
+
+// endpointKey identifies an endpoint.
+func endpointKey(endpoint Endpoint) string {
+	return endpoint.scheme() + "://" + endpoint.hostPort() + endpoint.pathPrefix()
+}
@@ -0,0 +1,221 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newDeadEndpoint returns an endpoint that refuses connections.
+func newDeadEndpoint(t *testing.T) Endpoint {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to listen: %+v", err)
+	}
+	host := listener.Addr().String()
+	listener.Close()
+
+	return Endpoint{Host: host}
+}
+
+func TestClientFailover(t *testing.T) {
+	var (
+		mutex    sync.Mutex
+		requests []string
+	)
+
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		mutex.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %+v", err)
+	}
+
+	dead := newDeadEndpoint(t)
+	live := Endpoint{Host: uri.Host, PathPrefix: "/live"}
+
+	newFailoverClient := func(options *FailoverOptions) *client {
+		return newClient(Options{
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Failover: options,
+		})
+	}
+
+	takeRequests := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		taken := requests
+		requests = nil
+		return taken
+	}
+
+	t.Run("idempotent requests fail over and eject the failed host", func(t *testing.T) {
+		c := newFailoverClient(&FailoverOptions{Endpoints: []Endpoint{dead, live}})
+
+		response, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/users"})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		response.Body.Close()
+
+		if taken := takeRequests(); len(taken) != 1 || taken[0] != "GET /live/users" {
+			t.Fatalf("Expected a request to GET /live/users, but got %v", taken)
+		}
+
+		endpoints, err := c.failover.endpoints(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+
+		if endpoints[0] != live || endpoints[1] != dead {
+			t.Fatalf("Expected the dead endpoint to be ejected, but got %v", endpoints)
+		}
+	})
+
+	t.Run("ejected hosts are used again after the cool-down", func(t *testing.T) {
+		c := newFailoverClient(&FailoverOptions{Endpoints: []Endpoint{dead, live}, Cooldown: time.Millisecond})
+		c.failover.eject(dead)
+		time.Sleep(5 * time.Millisecond)
+
+		endpoints, err := c.failover.endpoints(context.Background())
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+
+		if endpoints[0] != dead {
+			t.Fatalf("Expected the dead endpoint to be preferred again, but got %v", endpoints)
+		}
+	})
+
+	t.Run("non-idempotent requests do not fail over", func(t *testing.T) {
+		c := newFailoverClient(&FailoverOptions{Endpoints: []Endpoint{dead, live}})
+
+		_, err := c.Request(context.Background(), RequestOptions{
+			Method: "POST",
+			Path:   "/users",
+			Body:   strings.NewReader(`{"name": "alice"}`),
+		})
+		if err == nil {
+			t.Fatalf("Expected an error, but got none")
+		}
+
+		if taken := takeRequests(); len(taken) != 0 {
+			t.Fatalf("Expected no requests to the live host, but got %v", taken)
+		}
+
+		endpoints, _ := c.failover.endpoints(context.Background())
+		if endpoints[0] != live {
+			t.Fatalf("Expected the dead endpoint to be ejected, but got %v", endpoints)
+		}
+	})
+
+	t.Run("requests with an idempotency key fail over", func(t *testing.T) {
+		c := newFailoverClient(&FailoverOptions{Endpoints: []Endpoint{dead, live}})
+
+		response, err := c.Request(context.Background(), RequestOptions{
+			Method:         "POST",
+			Path:           "/users",
+			Body:           strings.NewReader(`{"name": "alice"}`),
+			IdempotencyKey: "key",
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		response.Body.Close()
+
+		if taken := takeRequests(); len(taken) != 1 || taken[0] != "POST /live/users" {
+			t.Fatalf("Expected a request to POST /live/users, but got %v", taken)
+		}
+	})
+
+	t.Run("redirect limits do not fail over", func(t *testing.T) {
+		redirecting := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Redirect(w, r, r.URL.Path, http.StatusFound)
+		}))
+		defer redirecting.Close()
+
+		redirectingURI, err := url.Parse(redirecting.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse server URL: %+v", err)
+		}
+		primary := Endpoint{Host: redirectingURI.Host}
+
+		c := newClient(Options{
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Failover:       &FailoverOptions{Endpoints: []Endpoint{primary, live}},
+			RedirectPolicy: &RedirectPolicy{MaxRedirects: 1},
+		})
+
+		_, err = c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/users"})
+		if err == nil || !strings.Contains(err.Error(), "Stopped after 1 redirects") {
+			t.Fatalf("Expected too many redirects error, but got %v", err)
+		}
+
+		if taken := takeRequests(); len(taken) != 0 {
+			t.Fatalf("Expected no requests to the live host, but got %v", taken)
+		}
+
+		endpoints, _ := c.failover.endpoints(context.Background())
+		if endpoints[0] != primary {
+			t.Fatalf("Expected the primary endpoint not to be ejected, but got %v", endpoints)
+		}
+	})
+
+	t.Run("resolver", func(t *testing.T) {
+		resolved := 0
+		c := newFailoverClient(&FailoverOptions{
+			Resolver: func(ctx context.Context) ([]Endpoint, error) {
+				resolved++
+				return []Endpoint{dead, live}, nil
+			},
+		})
+
+		for i := 0; i < 2; i++ {
+			response, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/users"})
+			if err != nil {
+				t.Fatalf("Expected no error, but got %+v", err)
+			}
+			response.Body.Close()
+		}
+
+		if resolved != 2 {
+			t.Fatalf("Expected the endpoints to be resolved 2 times, but got %d", resolved)
+		}
+
+		if taken := takeRequests(); len(taken) != 2 {
+			t.Fatalf("Expected 2 requests to the live host, but got %v", taken)
+		}
+	})
+
+	t.Run("invalid options", func(t *testing.T) {
+		err := Options{Failover: &FailoverOptions{}}.Validate()
+		if err == nil || err.Error() != "No failover endpoints provided" {
+			t.Fatalf("Expected no failover endpoints error, but got %v", err)
+		}
+
+		err = Options{Failover: &FailoverOptions{Endpoints: []Endpoint{{Host: "{cluster}.example.com"}}}}.Validate()
+		if err == nil || !strings.Contains(err.Error(), "unresolved") {
+			t.Fatalf("Expected unresolved placeholder error, but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,401 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	redacted               = "[REDACTED]"
+	defaultMaxLoggedBody   = 4096
+	logMessageRequest      = "platform request"
+	logMessageRequestBody  = "platform request body"
+	logMessageResponseBody = "platform response body"
+)
+
+// LogLevel is the severity of a log entry.
+type LogLevel int
+
+// Log levels, the zero value being LogLevelInfo.
+const (
+	LogLevelDebug LogLevel = iota - 1
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+// String returns the name of the log level.
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "debug"
+	case LogLevelInfo:
+		return "info"
+	case LogLevelWarn:
+		return "warn"
+	case LogLevelError:
+		return "error"
+	}
+
+	return "unknown"
+}
+
+// Logger receives structured log entries for requests made to the platform.
+type Logger interface {
+	Log(level LogLevel, message string, fields map[string]interface{})
+}
+
+// LoggerFunc allows using a function as a Logger.
+type LoggerFunc func(level LogLevel, message string, fields map[string]interface{})
+
+// Log calls the function.
+func (f LoggerFunc) Log(level LogLevel, message string, fields map[string]interface{}) {
+	f(level, message, fields)
+}
+
+// LoggingOptions configures what is logged for each request.
+//
+// Every request is logged with its method, path, query, status, latency and
+// request ID. Successful requests are logged at info level, client errors at
+// warn level and server or transport errors at error level.
+type LoggingOptions struct {
+	Level           LogLevel // Minimum level of the entries to log, defaults to LogLevelInfo
+	Headers         bool     // Include request and response headers
+	Bodies          bool     // Log request and response bodies, at debug level only
+	MaxBodySize     int      // Maximum number of body bytes logged, defaults to 4096
+	SensitiveFields []string // Headers, query parameters and JSON body fields to redact
+}
+
+// requestLogger logs requests with the configured options.
+type requestLogger struct {
+	logger  Logger
+	options LoggingOptions
+
+	// Matches sensitive fields in bodies that can not be decoded as JSON,
+	// such as JSON documents truncated to the maximum body size.
+	fieldPattern *regexp.Regexp
+}
+
+// newRequestLogger returns nil if no logger is configured.
+func newRequestLogger(logger Logger, options LoggingOptions) *requestLogger {
+	if logger == nil {
+		return nil
+	}
+
+	if options.MaxBodySize <= 0 {
+		options.MaxBodySize = defaultMaxLoggedBody
+	}
+
+	fields := []string{regexp.QuoteMeta(authorizationHeader)}
+	for _, field := range options.SensitiveFields {
+		fields = append(fields, regexp.QuoteMeta(field))
+	}
+
+	return &requestLogger{
+		logger:  logger,
+		options: options,
+		fieldPattern: regexp.MustCompile(
+			`("(?i:` + strings.Join(fields, "|") + `)"\s*:\s*)("(?:[^"\\]|\\.)*"?|[^,}\]\s]+)`,
+		),
+	}
+}
+
+// logsBodies reports whether bodies should be captured for logging.
+func (l *requestLogger) logsBodies() bool {
+	return l != nil && l.options.Bodies && l.options.Level <= LogLevelDebug
+}
+
+// captureRequestBody wraps the body of the request to record
+// what is sent, if bodies are logged.
+func (l *requestLogger) captureRequestBody(request *http.Request) *captureReader {
+	if !l.logsBodies() || request.Body == nil || request.Body == http.NoBody {
+		return nil
+	}
+
+	captured := &captureReader{ReadCloser: request.Body, limit: l.options.MaxBodySize}
+	request.Body = captured
+
+	return captured
+}
+
+// peekResponseBody reads the start of a successful response body
+// for logging, without consuming it for the caller.
+func (l *requestLogger) peekResponseBody(response *http.Response) []byte {
+	if !l.logsBodies() || response == nil || response.Body == nil {
+		return nil
+	}
+
+	peeked, _ := ioutil.ReadAll(io.LimitReader(response.Body, int64(l.options.MaxBodySize)))
+	response.Body = &peekedBody{io.MultiReader(bytes.NewReader(peeked), response.Body), response.Body}
+
+	return peeked
+}
+
+// logRequest logs a completed request.
+func (l *requestLogger) logRequest(
+	request *http.Request,
+	response *http.Response,
+	err error,
+	latency time.Duration,
+	requestBody *captureReader,
+	responseBody []byte,
+) {
+	if l == nil {
+		return
+	}
+
+	status := responseStatus(response, err)
+	level := LogLevelInfo
+	switch {
+	case err != nil && status == 0, status >= 500:
+		level = LogLevelError
+	case status >= 400:
+		level = LogLevelWarn
+	}
+
+	if level >= l.options.Level {
+		fields := map[string]interface{}{
+			"method":     request.Method,
+			"path":       request.URL.Path,
+			"query":      l.redactQuery(request.URL.Query()),
+			"status":     status,
+			"latency":    latency,
+			"request_id": responseRequestID(request, response, err),
+		}
+
+		if err != nil {
+			fields["error"] = err.Error()
+		}
+
+		if l.options.Headers {
+			fields["request_headers"] = l.redactHeaders(request.Header)
+			if response != nil {
+				fields["response_headers"] = l.redactHeaders(response.Header)
+			}
+		}
+
+		l.logger.Log(level, logMessageRequest, fields)
+	}
+
+	if !l.logsBodies() {
+		return
+	}
+
+	if captured := requestBody.bytes(); len(captured) > 0 {
+		l.logger.Log(LogLevelDebug, logMessageRequestBody, map[string]interface{}{
+			"method": request.Method,
+			"path":   request.URL.Path,
+			"body":   l.redactBody(captured),
+		})
+	}
+
+	if responseBody == nil {
+		responseBody = errorBody(err)
+	}
+
+	if len(responseBody) > 0 {
+		l.logger.Log(LogLevelDebug, logMessageResponseBody, map[string]interface{}{
+			"method": request.Method,
+			"path":   request.URL.Path,
+			"status": status,
+			"body":   l.redactBody(responseBody),
+		})
+	}
+}
+
+// isSensitive reports whether a header, query parameter or body field should be redacted.
+func (l *requestLogger) isSensitive(name string) bool {
+	if strings.EqualFold(name, authorizationHeader) {
+		return true
+	}
+
+	for _, field := range l.options.SensitiveFields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// redactHeaders returns a copy of the headers with sensitive values redacted.
+// Authorization headers keep their scheme, e.g. `Bearer [REDACTED]`.
+func (l *requestLogger) redactHeaders(headers http.Header) http.Header {
+	result := http.Header{}
+	for name, values := range headers {
+		if !l.isSensitive(name) {
+			result[name] = append([]string(nil), values...)
+			continue
+		}
+
+		for _, value := range values {
+			if scheme := strings.SplitN(value, " ", 2); len(scheme) == 2 {
+				result.Add(name, scheme[0]+" "+redacted)
+			} else {
+				result.Add(name, redacted)
+			}
+		}
+	}
+
+	return result
+}
+
+// redactQuery returns the encoded query with sensitive parameters redacted.
+func (l *requestLogger) redactQuery(query url.Values) string {
+	result := url.Values{}
+	for name, values := range query {
+		if l.isSensitive(name) {
+			result.Set(name, redacted)
+			continue
+		}
+
+		result[name] = values
+	}
+
+	return result.Encode()
+}
+
+// redactBody returns the body as a string, with sensitive fields
+// redacted if the body is a JSON document.
+func (l *requestLogger) redactBody(body []byte) string {
+	var document interface{}
+	if err := json.Unmarshal(body, &document); err != nil {
+		return l.fieldPattern.ReplaceAllString(string(body), `${1}"`+redacted+`"`)
+	}
+
+	redactedBody, err := json.Marshal(l.redactValue(document))
+	if err != nil {
+		return l.fieldPattern.ReplaceAllString(string(body), `${1}"`+redacted+`"`)
+	}
+
+	return string(redactedBody)
+}
+
+// redactValue recursively redacts sensitive fields of a decoded JSON value.
+func (l *requestLogger) redactValue(value interface{}) interface{} {
+	switch value := value.(type) {
+	case map[string]interface{}:
+		for key, field := range value {
+			if l.isSensitive(key) {
+				value[key] = redacted
+			} else {
+				value[key] = l.redactValue(field)
+			}
+		}
+	case []interface{}:
+		for i, element := range value {
+			value[i] = l.redactValue(element)
+		}
+	}
+
+	return value
+}
+
+// responseStatus returns the status code of a response, or of the
+// error response it was converted into.
+func responseStatus(response *http.Response, err error) int {
+	if response != nil {
+		return response.StatusCode
+	}
+
+	var platformError *PlatformError
+	if errors.As(err, &platformError) {
+		return platformError.Status
+	}
+
+	var bodyNotJSONError BodyNotJSONError
+	if errors.As(err, &bodyNotJSONError) {
+		return bodyNotJSONError.StatusCode
+	}
+
+	return 0
+}
+
+// responseRequestID returns the request ID reported by the platform,
+// falling back to the one sent with the request.
+func responseRequestID(request *http.Request, response *http.Response, err error) string {
+	if response != nil && response.Header.Get(requestIDHeader) != "" {
+		return response.Header.Get(requestIDHeader)
+	}
+
+	var platformError *PlatformError
+	if errors.As(err, &platformError) && platformError.RequestID != "" {
+		return platformError.RequestID
+	}
+
+	return request.Header.Get(requestIDHeader)
+}
+
+// errorBody returns the body of an error response, if it was read.
+func errorBody(err error) []byte {
+	var bodyNotJSONError BodyNotJSONError
+	if errors.As(err, &bodyNotJSONError) {
+		return bodyNotJSONError.BodyBytes
+	}
+
+	var platformError *PlatformError
+	if errors.As(err, &platformError) && platformError.Info != nil {
+		body, _ := json.Marshal(platformError.Info)
+		return body
+	}
+
+	return nil
+}
+
+// captureReader records up to limit bytes read from a request body.
+//
+// The body may still be read by the transport after the response has
+// been received, so access to the captured bytes is synchronised.
+type captureReader struct {
+	io.ReadCloser
+	limit int
+
+	mutex    sync.Mutex
+	captured bytes.Buffer
+}
+
+func (r *captureReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if remaining := r.limit - r.captured.Len(); remaining > 0 && n > 0 {
+		if remaining > n {
+			remaining = n
+		}
+		r.captured.Write(p[:remaining])
+	}
+
+	return n, err
+}
+
+// bytes returns a copy of the bytes captured so far.
+func (r *captureReader) bytes() []byte {
+	if r == nil {
+		return nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	return append([]byte(nil), r.captured.Bytes()...)
+}
+
+// peekedBody replays the peeked start of a response body before the rest of it.
+type peekedBody struct {
+	io.Reader
+	body io.ReadCloser
+}
+
+func (b *peekedBody) Close() error {
+	return b.body.Close()
+}
@@ -0,0 +1,197 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type logEntry struct {
+	level   LogLevel
+	message string
+	fields  map[string]interface{}
+}
+
+type testLogger struct {
+	mutex   sync.Mutex
+	entries []logEntry
+}
+
+func (l *testLogger) Log(level LogLevel, message string, fields map[string]interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.entries = append(l.entries, logEntry{level, message, fields})
+}
+
+func (l *testLogger) reset() []logEntry {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	entries := l.entries
+	l.entries = nil
+
+	return entries
+}
+
+func TestClientLogging(t *testing.T) {
+	jwt := "some.jwt.string"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services/test/v1/instance/users", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(requestIDHeader, "request-id")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"id": "user-id", "password": "hunter2"}`))
+	})
+	mux.HandleFunc("/services/test/v1/instance/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "not_found"}`))
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server url with error: %+v", err)
+	}
+
+	newLoggingClient := func(logger Logger, options LoggingOptions) Client {
+		return New(Options{
+			Host: uri.Host,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Logger:  logger,
+			Logging: options,
+		})
+	}
+
+	t.Run("Requests are logged with redacted headers and query", func(t *testing.T) {
+		logger := &testLogger{}
+		client := newLoggingClient(logger, LoggingOptions{
+			Headers:         true,
+			Bodies:          true,
+			SensitiveFields: []string{"token"},
+		})
+
+		_, err := client.Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/services/test/v1/instance/users",
+			Jwt:    &jwt,
+			QueryParams: &url.Values{
+				"token": []string{"secret"},
+				"limit": []string{"10"},
+			},
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		entries := logger.reset()
+		if len(entries) != 1 {
+			t.Fatalf("Expected a single entry without debug level, but got %d", len(entries))
+		}
+
+		entry := entries[0]
+		if entry.level != LogLevelInfo || entry.message != logMessageRequest {
+			t.Fatalf("Unexpected log entry %+v", entry)
+		}
+
+		if entry.fields["path"] != "/services/test/v1/instance/users" || entry.fields["status"] != http.StatusOK {
+			t.Fatalf("Unexpected log fields %+v", entry.fields)
+		}
+
+		if entry.fields["request_id"] != "request-id" {
+			t.Fatalf("Expected request ID to be logged, but got %v", entry.fields["request_id"])
+		}
+
+		if query := entry.fields["query"].(string); strings.Contains(query, "secret") || !strings.Contains(query, "limit=10") {
+			t.Fatalf("Expected token to be redacted from query, but got `%s`", query)
+		}
+
+		headers := entry.fields["request_headers"].(http.Header)
+		if authorization := headers.Get(authorizationHeader); authorization != "Bearer "+redacted {
+			t.Fatalf("Expected Authorization header to be redacted, but got `%s`", authorization)
+		}
+	})
+
+	t.Run("Bodies are logged at debug level with sensitive fields redacted", func(t *testing.T) {
+		logger := &testLogger{}
+		client := newLoggingClient(logger, LoggingOptions{
+			Level:           LogLevelDebug,
+			Bodies:          true,
+			SensitiveFields: []string{"password"},
+		})
+
+		resp, err := client.Request(context.Background(), RequestOptions{
+			Method: http.MethodPost,
+			Path:   "/services/test/v1/instance/users",
+			Body:   strings.NewReader(`{"name": "alice", "password": "hunter2"}`),
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		body, _ := ioutil.ReadAll(resp.Body)
+		if string(body) != `{"id": "user-id", "password": "hunter2"}` {
+			t.Fatalf("Expected the response body to be readable after logging, but got `%s`", body)
+		}
+
+		entries := logger.reset()
+		if len(entries) != 3 {
+			t.Fatalf("Expected request, request body and response body entries, but got %d", len(entries))
+		}
+
+		for _, entry := range entries[1:] {
+			if entry.level != LogLevelDebug {
+				t.Fatalf("Expected body entries to be logged at debug level, but got %v", entry.level)
+			}
+
+			if body := entry.fields["body"].(string); strings.Contains(body, "hunter2") {
+				t.Fatalf("Expected password to be redacted, but got `%s`", body)
+			}
+		}
+	})
+
+	t.Run("Error responses are logged at warn level", func(t *testing.T) {
+		logger := &testLogger{}
+		client := newLoggingClient(logger, LoggingOptions{
+			Level: LogLevelWarn,
+		})
+
+		_, err := client.Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/services/test/v1/instance/missing",
+		})
+		if err == nil {
+			t.Fatalf("Expected an error response")
+		}
+
+		entries := logger.reset()
+		if len(entries) != 1 || entries[0].level != LogLevelWarn || entries[0].fields["status"] != http.StatusNotFound {
+			t.Fatalf("Expected a warn entry with status 404, but got %+v", entries)
+		}
+	})
+}
+
+func TestRequestLoggerRedactsTruncatedBodies(t *testing.T) {
+	logger := newRequestLogger(&testLogger{}, LoggingOptions{
+		SensitiveFields: []string{"password"},
+	})
+
+	redactedBody := logger.redactBody([]byte(`{"name": "alice", "password": "hunter2", "nested": {"Password": "hun`))
+	if strings.Contains(redactedBody, "hunter2") || strings.Contains(redactedBody, "hun\"") {
+		t.Fatalf("Expected passwords to be redacted, but got `%s`", redactedBody)
+	}
+
+	if !strings.Contains(redactedBody, `"name": "alice"`) {
+		t.Fatalf("Expected other fields to be kept, but got `%s`", redactedBody)
+	}
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/pusher/pusher-platform-go/metrics"
+)
+
+// requestMetrics reports the requests of a client to the configured Metrics.
+type requestMetrics struct {
+	metrics metrics.Metrics
+	labels  metrics.Labels
+}
+
+// newRequestMetrics returns nil if no metrics are configured.
+func newRequestMetrics(m metrics.Metrics, labels metrics.Labels) *requestMetrics {
+	if m == nil {
+		return nil
+	}
+
+	return &requestMetrics{m, labels}
+}
+
+// countRequestBody wraps the body of the request to count the bytes sent.
+func (m *requestMetrics) countRequestBody(request *http.Request) *countingReader {
+	if m == nil {
+		return nil
+	}
+
+	counter := &countingReader{ReadCloser: request.Body}
+	if request.Body != nil && request.Body != http.NoBody {
+		request.Body = counter
+	}
+
+	return counter
+}
+
+// observe reports a request once it has completed.
+//
+// Successful responses are reported when their body has been read or closed,
+// so the number of bytes received can be included.
+func (m *requestMetrics) observe(
+	ctx context.Context,
+	request *http.Request,
+	response *http.Response,
+	err error,
+	duration time.Duration,
+	bytesOut *countingReader,
+	retry bool,
+) {
+	if m == nil {
+		return
+	}
+
+	retries := 0
+	if retry {
+		retries = 1
+	}
+
+	endpoint, ok := metrics.EndpointFromContext(ctx)
+	if !ok {
+		endpoint = metrics.UnknownEndpoint
+	}
+
+	observation := metrics.RequestObservation{
+		Labels:   metrics.LabelsFromContext(ctx, m.labels),
+		Method:   request.Method,
+		Endpoint: endpoint,
+		Host:     request.URL.Host,
+		Status:   responseStatus(response, err),
+		Duration: duration,
+		BytesOut: bytesOut.count(),
+		Retries:  retries,
+		Err:      err,
+	}
+
+	if response == nil || response.Body == nil {
+		m.metrics.ObserveRequest(observation)
+		return
+	}
+
+	response.Body = &observedBody{
+		countingReader: countingReader{ReadCloser: response.Body},
+		observe: func(bytesIn int64) {
+			observation.BytesIn = bytesIn
+			m.metrics.ObserveRequest(observation)
+		},
+	}
+}
+
+// countingReader counts the bytes read from a body.
+type countingReader struct {
+	io.ReadCloser
+	bytes int64
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.ReadCloser.Read(p)
+	atomic.AddInt64(&r.bytes, int64(n))
+
+	return n, err
+}
+
+func (r *countingReader) count() int64 {
+	if r == nil {
+		return 0
+	}
+
+	return atomic.LoadInt64(&r.bytes)
+}
+
+// observedBody reports the bytes read from a response body
+// once it has been read completely or closed.
+type observedBody struct {
+	countingReader
+	observe func(bytesIn int64)
+	once    sync.Once
+}
+
+func (b *observedBody) Read(p []byte) (int, error) {
+	n, err := b.countingReader.Read(p)
+	if err == io.EOF {
+		b.once.Do(func() { b.observe(b.count()) })
+	}
+
+	return n, err
+}
+
+func (b *observedBody) Close() error {
+	err := b.countingReader.Close()
+	b.once.Do(func() { b.observe(b.count()) })
+
+	return err
+}
@@ -0,0 +1,158 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pusher/pusher-platform-go/metrics"
+)
+
+type testMetrics struct {
+	mutex    sync.Mutex
+	requests []metrics.RequestObservation
+}
+
+func (m *testMetrics) ObserveRequest(observation metrics.RequestObservation) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.requests = append(m.requests, observation)
+}
+
+func (m *testMetrics) ObserveAuthentication(observation metrics.AuthenticationObservation) {}
+
+func (m *testMetrics) observations() []metrics.RequestObservation {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	return append([]metrics.RequestObservation(nil), m.requests...)
+}
+
+func TestClientMetrics(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("hello world"))
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "not_found"}`))
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server url with error: %+v", err)
+	}
+
+	m := &testMetrics{}
+	client := New(Options{
+		Host: uri.Host,
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+		Metrics:       m,
+		MetricsLabels: metrics.Labels{Service: "default", Cluster: "local"},
+	})
+
+	ctx := metrics.ContextWithLabels(context.Background(), metrics.Labels{Service: "chat"})
+	ctx = metrics.ContextWithEndpoint(ctx, "/users/:id")
+	resp, err := client.Request(ctx, RequestOptions{
+		Method: http.MethodPost,
+		Path:   "/users",
+		Body:   strings.NewReader("hello"),
+	})
+	if err != nil {
+		t.Fatalf("Failed to request backend resource with error: %+v", err)
+	}
+
+	if len(m.observations()) != 0 {
+		t.Fatalf("Expected successful requests to be observed once the body is read")
+	}
+
+	ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	_, err = client.Request(context.Background(), RequestOptions{
+		Method: http.MethodGet,
+		Path:   "/missing",
+	})
+	if err == nil {
+		t.Fatalf("Expected an error response")
+	}
+
+	observations := m.observations()
+	if len(observations) != 2 {
+		t.Fatalf("Expected 2 observations, but got %d", len(observations))
+	}
+
+	success := observations[0]
+	if success.Labels.Service != "chat" || success.Labels.Cluster != "local" {
+		t.Fatalf("Expected labels to be merged with the defaults, but got %+v", success.Labels)
+	}
+
+	if success.Endpoint != "/users/:id" || success.StatusClass() != "2xx" {
+		t.Fatalf("Unexpected observation %+v", success)
+	}
+
+	if success.BytesOut != 5 || success.BytesIn != 11 {
+		t.Fatalf("Expected 5 bytes out and 11 bytes in, but got %d and %d", success.BytesOut, success.BytesIn)
+	}
+
+	failure := observations[1]
+	if failure.Endpoint != metrics.UnknownEndpoint || failure.Status != http.StatusNotFound || failure.Err == nil {
+		t.Fatalf("Unexpected observation %+v", failure)
+	}
+}
+
+func TestClientMetricsRetries(t *testing.T) {
+	var failures int32 = 3
+	m := metrics.NewInMemory()
+	client, closeServer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&failures, -1) >= 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "unavailable"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+	}), Options{
+		Metrics:     m,
+		RetryPolicy: &RetryPolicy{MaxRetries: 3, Backoff: Backoff{Initial: time.Millisecond}},
+	})
+	defer closeServer()
+
+	response, err := client.Request(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/users"})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %+v", err)
+	}
+	response.Body.Close()
+
+	var output strings.Builder
+	if err := m.WritePrometheus(&output); err != nil {
+		t.Fatalf("Expected no error when writing metrics, but got %+v", err)
+	}
+
+	for _, line := range []string{
+		`pusher_platform_requests_total{service="",version="",cluster="",method="GET",endpoint="unknown",status_class="5xx"} 3`,
+		`pusher_platform_requests_total{service="",version="",cluster="",method="GET",endpoint="unknown",status_class="2xx"} 1`,
+		`pusher_platform_request_retries_total{service="",version="",cluster="",method="GET",endpoint="unknown",status_class="5xx"} 2`,
+		`pusher_platform_request_retries_total{service="",version="",cluster="",method="GET",endpoint="unknown",status_class="2xx"} 1`,
+	} {
+		if !strings.Contains(output.String(), line) {
+			t.Fatalf("Expected metrics to contain `%s`, but got:\n%s", line, output.String())
+		}
+	}
+}
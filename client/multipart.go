@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const defaultFileContentType = "application/octet-stream"
+
+// MultipartFile is a file sent as part of a multipart upload.
+type MultipartFile struct {
+	FieldName   string    // Name of the form field
+	FileName    string    // Name of the file
+	ContentType string    // Content type of the file, defaults to application/octet-stream
+	Content     io.Reader // Content of the file, closed after it is sent if it is an io.Closer
+	Size        int64     // Size of the content in bytes, zero or negative if unknown
+}
+
+// NewMultipartFile opens the file at the given path to be sent in a multipart upload.
+//
+// The file is closed once it has been sent.
+func NewMultipartFile(fieldName string, path string) (MultipartFile, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return MultipartFile{}, err
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		_ = file.Close()
+		return MultipartFile{}, err
+	}
+
+	return MultipartFile{
+		FieldName: fieldName,
+		FileName:  filepath.Base(path),
+		Content:   file,
+		Size:      info.Size(),
+	}, nil
+}
+
+// MultipartUpload describes a multipart/form-data request body.
+//
+// The body is streamed as it is sent, so files are never buffered in memory.
+type MultipartUpload struct {
+	Fields map[string]string // Form fields, sent before the files
+	Files  []MultipartFile   // Files to send
+
+	// Progress is an optional callback called as the body is sent, with the
+	// number of bytes sent so far and the total size, which is negative if unknown.
+	Progress func(sent int64, total int64)
+}
+
+// Body returns a reader that streams the multipart body along with its content
+// type, which includes the boundary, and its length, which is negative if unknown.
+//
+// Sending the body stops with the context error when the context is done.
+// Closing the reader stops sending the body and closes the file contents.
+func (u MultipartUpload) Body(ctx context.Context) (io.ReadCloser, string, int64) {
+	reader, writer := io.Pipe()
+	boundary := multipart.NewWriter(nil).Boundary()
+	total := u.contentLength(boundary)
+
+	multipartWriter := multipart.NewWriter(&progressWriter{writer: writer, total: total, callback: u.Progress})
+	_ = multipartWriter.SetBoundary(boundary)
+	contentType := multipartWriter.FormDataContentType()
+
+	go func() {
+		err := u.write(ctx, multipartWriter)
+		u.closeFiles()
+		_ = writer.CloseWithError(err)
+	}()
+
+	return reader, contentType, total
+}
+
+// write writes the fields and files of the upload.
+func (u MultipartUpload) write(ctx context.Context, writer *multipart.Writer) error {
+	for _, name := range u.fieldNames() {
+		if err := writer.WriteField(name, u.Fields[name]); err != nil {
+			return err
+		}
+	}
+
+	for _, file := range u.Files {
+		part, err := writer.CreatePart(file.header())
+		if err != nil {
+			return err
+		}
+
+		if file.Content != nil {
+			if _, err := io.Copy(part, &contextReader{ctx, file.Content}); err != nil {
+				return err
+			}
+		}
+	}
+
+	return writer.Close()
+}
+
+// contentLength returns the length of the body, or -1 if the size of a file is unknown.
+func (u MultipartUpload) contentLength(boundary string) int64 {
+	counter := &countingWriter{}
+	writer := multipart.NewWriter(counter)
+	_ = writer.SetBoundary(boundary)
+
+	for _, name := range u.fieldNames() {
+		_ = writer.WriteField(name, u.Fields[name])
+	}
+
+	var size int64
+	for _, file := range u.Files {
+		if file.Content != nil && file.Size <= 0 {
+			return -1
+		}
+
+		_, _ = writer.CreatePart(file.header())
+		if file.Content != nil {
+			size += file.Size
+		}
+	}
+	_ = writer.Close()
+
+	return counter.written + size
+}
+
+// fieldNames returns the names of the fields in a stable order.
+func (u MultipartUpload) fieldNames() []string {
+	names := make([]string, 0, len(u.Fields))
+	for name := range u.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// closeFiles closes the contents of the files that are closers.
+func (u MultipartUpload) closeFiles() {
+	for _, file := range u.Files {
+		if closer, ok := file.Content.(io.Closer); ok {
+			_ = closer.Close()
+		}
+	}
+}
+
+// header returns the MIME header of the file part.
+func (f MultipartFile) header() textproto.MIMEHeader {
+	contentType := f.ContentType
+	if contentType == "" {
+		contentType = defaultFileContentType
+	}
+
+	header := textproto.MIMEHeader{}
+	header.Set(
+		"Content-Disposition",
+		fmt.Sprintf(`form-data; name="%s"; filename="%s"`, escapeQuotes(f.FieldName), escapeQuotes(f.FileName)),
+	)
+	header.Set("Content-Type", contentType)
+
+	return header
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func escapeQuotes(s string) string {
+	return quoteEscaper.Replace(s)
+}
+
+// contextReader stops reading once the context is done.
+type contextReader struct {
+	ctx    context.Context
+	reader io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+
+	return r.reader.Read(p)
+}
+
+// progressWriter reports the bytes written to the body.
+type progressWriter struct {
+	writer   io.Writer
+	total    int64
+	sent     int64
+	callback func(sent int64, total int64)
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.writer.Write(p)
+	w.sent += int64(n)
+	if w.callback != nil && n > 0 {
+		w.callback(w.sent, w.total)
+	}
+
+	return n, err
+}
+
+// countingWriter counts the bytes written to it.
+type countingWriter struct {
+	written int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.written += int64(len(p))
+	return len(p), nil
+}
@@ -0,0 +1,167 @@
+package client
+
+import (
+	"context"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func readMultipart(t *testing.T, body io.Reader, contentType string) (map[string]string, map[string]*multipart.Part, map[string]string) {
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil || mediaType != "multipart/form-data" {
+		t.Fatalf("Expected a multipart/form-data content type, but got %s", contentType)
+	}
+
+	fields := map[string]string{}
+	files := map[string]*multipart.Part{}
+	contents := map[string]string{}
+
+	reader := multipart.NewReader(body, params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected no error reading the multipart body, but got %+v", err)
+		}
+
+		content, err := ioutil.ReadAll(part)
+		if err != nil {
+			t.Fatalf("Expected no error reading a part, but got %+v", err)
+		}
+
+		if part.FileName() == "" {
+			fields[part.FormName()] = string(content)
+		} else {
+			files[part.FormName()] = part
+			contents[part.FormName()] = string(content)
+		}
+	}
+
+	return fields, files, contents
+}
+
+func TestMultipartUploadBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "avatar.png")
+	if err := ioutil.WriteFile(path, []byte("image data"), 0600); err != nil {
+		t.Fatalf("Failed to write file: %+v", err)
+	}
+
+	file, err := NewMultipartFile("avatar", path)
+	if err != nil {
+		t.Fatalf("Expected no error opening the file, but got %+v", err)
+	}
+
+	var sent, total int64
+	upload := MultipartUpload{
+		Fields: map[string]string{"name": "alice", "role": "admin"},
+		Files: []MultipartFile{
+			file,
+			{
+				FieldName:   "notes",
+				FileName:    `my "notes".txt`,
+				ContentType: "text/plain",
+				Content:     strings.NewReader("some notes"),
+				Size:        int64(len("some notes")),
+			},
+		},
+		Progress: func(s int64, t int64) {
+			sent, total = s, t
+		},
+	}
+
+	body, contentType, length := upload.Body(context.Background())
+	defer body.Close()
+
+	data, err := ioutil.ReadAll(body)
+	if err != nil {
+		t.Fatalf("Expected no error reading the body, but got %+v", err)
+	}
+
+	if length != int64(len(data)) {
+		t.Fatalf("Expected length %d, but got %d", len(data), length)
+	}
+
+	if sent != length || total != length {
+		t.Fatalf("Expected progress of %d of %d bytes, but got %d of %d", length, length, sent, total)
+	}
+
+	fields, files, contents := readMultipart(t, strings.NewReader(string(data)), contentType)
+	if fields["name"] != "alice" || fields["role"] != "admin" {
+		t.Fatalf("Expected the fields to be sent, but got %v", fields)
+	}
+
+	if files["avatar"] == nil || files["avatar"].FileName() != "avatar.png" || contents["avatar"] != "image data" {
+		t.Fatalf("Expected the avatar file to be sent, but got %v", contents)
+	}
+
+	if got := files["avatar"].Header.Get("Content-Type"); got != defaultFileContentType {
+		t.Fatalf("Expected content type %s, but got %s", defaultFileContentType, got)
+	}
+
+	if files["notes"] == nil || files["notes"].FileName() != `my "notes".txt` || contents["notes"] != "some notes" {
+		t.Fatalf("Expected the notes file to be sent, but got %v", contents)
+	}
+
+	if err := file.Content.(*os.File).Close(); err == nil {
+		t.Fatalf("Expected the file to be closed after it was sent")
+	}
+}
+
+func TestMultipartUploadUnknownSize(t *testing.T) {
+	var total int64
+	upload := MultipartUpload{
+		Files: []MultipartFile{
+			{FieldName: "data", FileName: "data.bin", Content: strings.NewReader("data")},
+		},
+		Progress: func(s int64, t int64) {
+			total = t
+		},
+	}
+
+	body, _, length := upload.Body(context.Background())
+	defer body.Close()
+
+	if _, err := ioutil.ReadAll(body); err != nil {
+		t.Fatalf("Expected no error reading the body, but got %+v", err)
+	}
+
+	if length != -1 || total != -1 {
+		t.Fatalf("Expected an unknown length, but got %d and %d", length, total)
+	}
+}
+
+func TestMultipartUploadCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	upload := MultipartUpload{
+		Files: []MultipartFile{
+			{
+				FieldName: "data",
+				FileName:  "data.bin",
+				Content:   strings.NewReader(strings.Repeat("data", 1<<16)),
+				Size:      4 << 16,
+			},
+		},
+	}
+
+	body, _, _ := upload.Body(ctx)
+	defer body.Close()
+
+	if _, err := body.Read(make([]byte, 16)); err != nil {
+		t.Fatalf("Expected no error reading the body, but got %+v", err)
+	}
+
+	cancel()
+
+	if _, err := ioutil.ReadAll(body); err != context.Canceled {
+		t.Fatalf("Expected the context to cancel the upload, but got %+v", err)
+	}
+}
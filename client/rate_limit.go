@@ -0,0 +1,251 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Prefixes of the rate limit headers sent by the platform, which are
+// followed by `Limit`, `Remaining` and `Reset`.
+var rateLimitHeaderPrefixes = []string{"X-RateLimit-", "RateLimit-"}
+
+// resetEpochThreshold separates reset headers holding a number of seconds
+// from those holding a Unix timestamp.
+const resetEpochThreshold = 1000000000
+
+// ErrRateLimitDeadline is returned when the rate limiter would have to wait
+// beyond the deadline of the request context before sending the request.
+var ErrRateLimitDeadline = errors.New("Rate limit would delay the request beyond its deadline")
+
+// RateLimitOptions configures a RateLimiter.
+type RateLimitOptions struct {
+	Rate  float64 // Requests per second allowed to each host, only rate limit headers are followed if zero
+	Burst int     // Requests that can be sent at once to each host, defaults to 1
+}
+
+// RateLimitStats reports how much a RateLimiter has throttled requests.
+type RateLimitStats struct {
+	Requests    int64         // Requests allowed through the limiter
+	Throttled   int64         // Requests that had to wait before being sent
+	Rejected    int64         // Requests that failed because their context ended before they could be sent
+	Waited      time.Duration // Total time requests waited
+	RateLimited int64         // Rate limited responses received from the platform
+}
+
+// RateLimiter paces the requests of clients with a token bucket per host.
+//
+// The pace is slowed down according to the rate limit headers of responses,
+// and requests wait until the time given by the Retry-After header of a 429
+// response has passed. Requests wait for as long as their context allows, and fail
+// straight away with ErrRateLimitDeadline if their deadline would be exceeded.
+//
+// A RateLimiter can be shared by several clients. Subscriptions are not rate limited.
+type RateLimiter struct {
+	options RateLimitOptions
+
+	mutex   sync.Mutex
+	buckets map[string]*tokenBucket
+	stats   RateLimitStats
+}
+
+// tokenBucket holds the pace of requests to a single host.
+type tokenBucket struct {
+	tokens       float64
+	last         time.Time
+	pausedUntil  time.Time // Requests wait until then, as requested by the platform
+	adaptedRate  float64   // Rate derived from the rate limit headers
+	adaptedUntil time.Time // The adapted rate applies until the rate limit window resets
+}
+
+// NewRateLimiter builds a new RateLimiter.
+func NewRateLimiter(options RateLimitOptions) *RateLimiter {
+	if options.Burst <= 0 {
+		options.Burst = 1
+	}
+
+	return &RateLimiter{
+		options: options,
+		buckets: map[string]*tokenBucket{},
+	}
+}
+
+// Stats returns how much requests have been throttled so far.
+func (l *RateLimiter) Stats() RateLimitStats {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return l.stats
+}
+
+// wait blocks until a request can be sent to the host.
+func (l *RateLimiter) wait(ctx context.Context, host string) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mutex.Lock()
+	now := time.Now()
+	delay := l.reserve(host, now)
+	if deadline, ok := ctx.Deadline(); ok && now.Add(delay).After(deadline) {
+		l.cancel(host)
+		l.stats.Rejected++
+		l.mutex.Unlock()
+		return ErrRateLimitDeadline
+	}
+	l.mutex.Unlock()
+
+	if delay <= 0 {
+		l.record(0)
+		return nil
+	}
+
+	if err := sleep(ctx, delay); err != nil {
+		l.mutex.Lock()
+		l.cancel(host)
+		l.stats.Rejected++
+		l.mutex.Unlock()
+		return err
+	}
+
+	l.record(delay)
+	return nil
+}
+
+// record counts a request allowed through after waiting for the given delay.
+func (l *RateLimiter) record(delay time.Duration) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	l.stats.Requests++
+	if delay > 0 {
+		l.stats.Throttled++
+		l.stats.Waited += delay
+	}
+}
+
+// reserve takes a token from the bucket of the host and returns how long
+// to wait before sending the request. It must be called with the lock held.
+func (l *RateLimiter) reserve(host string, now time.Time) time.Duration {
+	bucket, ok := l.buckets[host]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(l.options.Burst), last: now}
+		l.buckets[host] = bucket
+	}
+
+	var delay time.Duration
+	if rate := l.rate(bucket, now); rate > 0 {
+		elapsed := now.Sub(bucket.last).Seconds()
+		bucket.tokens = math.Min(float64(l.options.Burst), bucket.tokens+elapsed*rate)
+		bucket.tokens--
+		if bucket.tokens < 0 {
+			delay = time.Duration(-bucket.tokens / rate * float64(time.Second))
+		}
+	}
+	bucket.last = now
+
+	if pause := bucket.pausedUntil.Sub(now); pause > delay {
+		delay = pause
+	}
+
+	return delay
+}
+
+// cancel returns the token of a request that was not sent.
+// It must be called with the lock held.
+func (l *RateLimiter) cancel(host string) {
+	if bucket, ok := l.buckets[host]; ok && l.rate(bucket, time.Now()) > 0 {
+		bucket.tokens++
+	}
+}
+
+// rate returns the requests per second currently allowed by the bucket.
+func (l *RateLimiter) rate(bucket *tokenBucket, now time.Time) float64 {
+	rate := l.options.Rate
+	if now.Before(bucket.adaptedUntil) && bucket.adaptedRate > 0 && (rate <= 0 || bucket.adaptedRate < rate) {
+		rate = bucket.adaptedRate
+	}
+
+	return rate
+}
+
+// update adjusts the pace of requests to the host from the headers of a response.
+func (l *RateLimiter) update(host string, response *http.Response, err error) {
+	if l == nil {
+		return
+	}
+
+	status, headers := responseHeaders(response, err)
+	if headers == nil {
+		return
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	bucket, ok := l.buckets[host]
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if status == http.StatusTooManyRequests {
+		l.stats.RateLimited++
+		if delay, ok := retryAfter(headers); ok {
+			bucket.pausedUntil = now.Add(delay)
+		}
+	}
+
+	remaining, hasRemaining := rateLimitHeader(headers, "Remaining")
+	reset, hasReset := rateLimitHeader(headers, "Reset")
+	if !hasRemaining || !hasReset {
+		return
+	}
+
+	resetAt := now.Add(time.Duration(reset * float64(time.Second)))
+	if reset > resetEpochThreshold {
+		resetAt = time.Unix(int64(reset), 0)
+	}
+
+	if remaining < 1 {
+		if resetAt.After(bucket.pausedUntil) {
+			bucket.pausedUntil = resetAt
+		}
+		return
+	}
+
+	if window := resetAt.Sub(now).Seconds(); window > 0 {
+		bucket.adaptedRate = remaining / window
+		bucket.adaptedUntil = resetAt
+	}
+}
+
+// rateLimitHeader returns the value of the rate limit header with the given suffix.
+func rateLimitHeader(headers http.Header, suffix string) (float64, bool) {
+	for _, prefix := range rateLimitHeaderPrefixes {
+		value, err := strconv.ParseFloat(headers.Get(prefix+suffix), 64)
+		if err == nil && value >= 0 {
+			return value, true
+		}
+	}
+
+	return 0, false
+}
+
+// responseHeaders returns the status and headers of a response, or of the error response it failed with.
+func responseHeaders(response *http.Response, err error) (int, http.Header) {
+	if response != nil {
+		return response.StatusCode, response.Header
+	}
+
+	var errorResponse *ErrorResponse
+	if errors.As(err, &errorResponse) {
+		return errorResponse.Status, errorResponse.Headers
+	}
+
+	return 0, nil
+}
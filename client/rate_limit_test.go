@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClientRateLimiter(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/limited", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-RateLimit-Limit", "10")
+		w.Header().Set("X-RateLimit-Remaining", "0")
+		w.Header().Set("X-RateLimit-Reset", "0.2")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error": "rate_limited"}`))
+	})
+
+	request := func(t *testing.T, client Client, ctx context.Context, path string) error {
+		response, err := client.Request(ctx, RequestOptions{Method: http.MethodGet, Path: path})
+		if err == nil {
+			response.Body.Close()
+		}
+
+		return err
+	}
+
+	t.Run("paces requests", func(t *testing.T) {
+		rateLimiter := NewRateLimiter(RateLimitOptions{Rate: 20})
+		client, closeServer := newTestClient(t, mux, Options{RateLimiter: rateLimiter})
+		defer closeServer()
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			if err := request(t, client, context.Background(), "/ok"); err != nil {
+				t.Fatalf("Expected no error, but got %+v", err)
+			}
+		}
+
+		if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+			t.Fatalf("Expected the requests to be paced, but they took %s", elapsed)
+		}
+
+		stats := rateLimiter.Stats()
+		if stats.Requests != 3 || stats.Throttled != 2 || stats.Waited <= 0 {
+			t.Fatalf("Expected 2 of 3 requests to be throttled, but got %+v", stats)
+		}
+	})
+
+	t.Run("fails when the deadline would be exceeded", func(t *testing.T) {
+		rateLimiter := NewRateLimiter(RateLimitOptions{Rate: 1})
+		client, closeServer := newTestClient(t, mux, Options{RateLimiter: rateLimiter})
+		defer closeServer()
+
+		if err := request(t, client, context.Background(), "/ok"); err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+
+		start := time.Now()
+		if err := request(t, client, ctx, "/ok"); !errors.Is(err, ErrRateLimitDeadline) {
+			t.Fatalf("Expected a rate limit deadline error, but got %+v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed > 50*time.Millisecond {
+			t.Fatalf("Expected the request to fail straight away, but it took %s", elapsed)
+		}
+
+		if stats := rateLimiter.Stats(); stats.Rejected != 1 {
+			t.Fatalf("Expected 1 rejected request, but got %+v", stats)
+		}
+	})
+
+	t.Run("follows rate limit headers", func(t *testing.T) {
+		rateLimiter := NewRateLimiter(RateLimitOptions{})
+		client, closeServer := newTestClient(t, mux, Options{RateLimiter: rateLimiter})
+		defer closeServer()
+
+		if err := request(t, client, context.Background(), "/limited"); !errors.Is(err, ErrRateLimited) {
+			t.Fatalf("Expected a rate limited error, but got %+v", err)
+		}
+
+		start := time.Now()
+		if err := request(t, client, context.Background(), "/ok"); err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+
+		if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+			t.Fatalf("Expected the request to wait for the rate limit to reset, but it took %s", elapsed)
+		}
+
+		stats := rateLimiter.Stats()
+		if stats.RateLimited != 1 || stats.Throttled != 1 {
+			t.Fatalf("Expected 1 rate limited and 1 throttled request, but got %+v", stats)
+		}
+	})
+}
+
+func TestRateLimiterAdaptsToRemainingRequests(t *testing.T) {
+	rateLimiter := NewRateLimiter(RateLimitOptions{Rate: 100, Burst: 10})
+	now := time.Now()
+	rateLimiter.reserve("host", now)
+
+	rateLimiter.update("host", &http.Response{
+		StatusCode: http.StatusOK,
+		Header: http.Header{
+			"Ratelimit-Remaining": []string{"2"},
+			"Ratelimit-Reset":     []string{"10"},
+		},
+	}, nil)
+
+	bucket := rateLimiter.buckets["host"]
+	if rate := rateLimiter.rate(bucket, now); rate < 0.19 || rate > 0.21 {
+		t.Fatalf("Expected the rate to slow down to 0.2 requests per second, but got %f", rate)
+	}
+
+	if rate := rateLimiter.rate(bucket, now.Add(11*time.Second)); rate != 100 {
+		t.Fatalf("Expected the rate to be restored after the reset, but got %f", rate)
+	}
+}
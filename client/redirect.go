@@ -0,0 +1,126 @@
+package client
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const defaultMaxRedirects = 10
+
+// RedirectPolicy configures how redirect responses are followed.
+//
+// The Authorization header is only sent to the host of the original request
+// and the allowed hosts, and never over a connection downgraded from https.
+type RedirectPolicy struct {
+	MaxRedirects int      // Maximum number of redirects to follow, defaults to 10
+	AllowedHosts []string // Other hosts that may receive the Authorization header
+}
+
+// checkRedirect is used as the CheckRedirect function of the underlying http client.
+func (p RedirectPolicy) checkRedirect(request *http.Request, via []*http.Request) error {
+	maxRedirects := p.MaxRedirects
+	if maxRedirects <= 0 {
+		maxRedirects = defaultMaxRedirects
+	}
+
+	if len(via) > maxRedirects {
+		return fmt.Errorf("Stopped after %d redirects", maxRedirects)
+	}
+
+	original := via[0]
+	authorization := original.Header.Get(authorizationHeader)
+	if authorization == "" {
+		return nil
+	}
+
+	if p.allowsCredentials(original.URL, request.URL) {
+		request.Header.Set(authorizationHeader, authorization)
+	} else {
+		request.Header.Del(authorizationHeader)
+	}
+
+	return nil
+}
+
+// allowsCredentials reports whether credentials sent to the
+// original URL may also be sent to the redirect target.
+func (p RedirectPolicy) allowsCredentials(original *url.URL, target *url.URL) bool {
+	if original.Scheme == "https" && target.Scheme != "https" {
+		return false
+	}
+
+	if strings.EqualFold(original.Host, target.Host) {
+		return true
+	}
+
+	for _, host := range p.AllowedHosts {
+		if strings.EqualFold(host, target.Host) || strings.EqualFold(host, target.Hostname()) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// RedirectChain returns the URLs that responded with a redirect
+// before the given response was received, in the order they were requested.
+func RedirectChain(response *http.Response) []*url.URL {
+	var chain []*url.URL
+	if response == nil || response.Request == nil {
+		return chain
+	}
+
+	for redirect := response.Request.Response; redirect != nil && redirect.Request != nil; redirect = redirect.Request.Response {
+		chain = append([]*url.URL{redirect.Request.URL}, chain...)
+	}
+
+	return chain
+}
+
+// FinalURL returns the URL of the request that produced the response, after any redirects.
+func FinalURL(response *http.Response) *url.URL {
+	if response == nil || response.Request == nil {
+		return nil
+	}
+
+	return response.Request.URL
+}
+
+// setGetBody allows bodies that can be rewound to be sent again when
+// following 307 and 308 redirects, which preserve the method and body.
+//
+// Bodies of the types supported by http.NewRequest are already handled.
+// Bodies that have to be closed, such as files, are left alone, since the
+// transport closes them after the first request.
+func setGetBody(request *http.Request, body io.Reader) {
+	if request.GetBody != nil || body == nil {
+		return
+	}
+
+	if _, ok := body.(io.Closer); ok {
+		return
+	}
+
+	seeker, ok := body.(io.ReadSeeker)
+	if !ok {
+		return
+	}
+
+	offset, err := seeker.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return
+	}
+
+	request.Body = ioutil.NopCloser(seeker)
+	request.GetBody = func() (io.ReadCloser, error) {
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+
+		return ioutil.NopCloser(seeker), nil
+	}
+}
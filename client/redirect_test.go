@@ -0,0 +1,191 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestClientRedirectPolicy(t *testing.T) {
+	jwt := "some.jwt.string"
+
+	var (
+		receivedAuthorization string
+		receivedBody          string
+	)
+
+	other := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedAuthorization = r.Header.Get(authorizationHeader)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer other.Close()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/same_host", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/target", http.StatusFound)
+	})
+	mux.HandleFunc("/other_host", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, other.URL+"/target", http.StatusFound)
+	})
+	mux.HandleFunc("/loop", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/loop", http.StatusFound)
+	})
+	mux.HandleFunc("/post", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/target", http.StatusTemporaryRedirect)
+	})
+	mux.HandleFunc("/target", func(w http.ResponseWriter, r *http.Request) {
+		receivedAuthorization = r.Header.Get(authorizationHeader)
+		bodyBytes, _ := ioutil.ReadAll(r.Body)
+		receivedBody = string(bodyBytes)
+		w.WriteHeader(http.StatusOK)
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server url with error: %+v", err)
+	}
+
+	otherURI, err := url.Parse(other.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server url with error: %+v", err)
+	}
+
+	newRedirectClient := func(policy *RedirectPolicy) Client {
+		return New(Options{
+			Host: uri.Host,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			RedirectPolicy: policy,
+		})
+	}
+
+	t.Run("Credentials are kept for the same host", func(t *testing.T) {
+		receivedAuthorization = ""
+		resp, err := newRedirectClient(nil).Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/same_host",
+			Jwt:    &jwt,
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		if receivedAuthorization != "Bearer "+jwt {
+			t.Fatalf("Expected the Authorization header to be kept, but got `%s`", receivedAuthorization)
+		}
+
+		if FinalURL(resp).Path != "/target" {
+			t.Fatalf("Expected final URL to be /target, but got %s", FinalURL(resp))
+		}
+
+		chain := RedirectChain(resp)
+		if len(chain) != 1 || chain[0].Path != "/same_host" {
+			t.Fatalf("Expected redirect chain to contain /same_host, but got %v", chain)
+		}
+	})
+
+	t.Run("Credentials are removed for other hosts", func(t *testing.T) {
+		receivedAuthorization = ""
+		_, err := newRedirectClient(nil).Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/other_host",
+			Jwt:    &jwt,
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		if receivedAuthorization != "" {
+			t.Fatalf("Expected the Authorization header to be removed, but got `%s`", receivedAuthorization)
+		}
+	})
+
+	t.Run("Credentials are kept for allowed hosts", func(t *testing.T) {
+		receivedAuthorization = ""
+		_, err := newRedirectClient(&RedirectPolicy{
+			AllowedHosts: []string{otherURI.Host},
+		}).Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/other_host",
+			Jwt:    &jwt,
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		if receivedAuthorization != "Bearer "+jwt {
+			t.Fatalf("Expected the Authorization header to be kept, but got `%s`", receivedAuthorization)
+		}
+	})
+
+	t.Run("Redirects are limited", func(t *testing.T) {
+		_, err := newRedirectClient(&RedirectPolicy{
+			MaxRedirects: 3,
+		}).Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/loop",
+		})
+		if err == nil || !strings.Contains(err.Error(), "Stopped after 3 redirects") {
+			t.Fatalf("Expected too many redirects error, but got %v", err)
+		}
+	})
+
+	t.Run("Bodies are sent again for 307 redirects", func(t *testing.T) {
+		receivedBody = ""
+		_, err := newRedirectClient(nil).Request(context.Background(), RequestOptions{
+			Method: http.MethodPost,
+			Path:   "/post",
+			Body:   strings.NewReader("hello"),
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		if receivedBody != "hello" {
+			t.Fatalf("Expected body to be `hello`, but got `%s`", receivedBody)
+		}
+	})
+
+	t.Run("Bodies that have to be closed are closed", func(t *testing.T) {
+		file, err := ioutil.TempFile(t.TempDir(), "body")
+		if err != nil {
+			t.Fatalf("Failed to create body file: %+v", err)
+		}
+
+		response, err := newRedirectClient(nil).Request(context.Background(), RequestOptions{
+			Method: http.MethodPut,
+			Path:   "/target",
+			Body:   file,
+		})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+		response.Body.Close()
+
+		if err := file.Close(); !errors.Is(err, os.ErrClosed) {
+			t.Fatalf("Expected the body file to be closed, but got %v", err)
+		}
+	})
+
+	t.Run("Bodies that can not be sent again are reported", func(t *testing.T) {
+		_, err := newRedirectClient(nil).Request(context.Background(), RequestOptions{
+			Method: http.MethodPost,
+			Path:   "/post",
+			Body:   ioutil.NopCloser(strings.NewReader("hello")),
+		})
+		if err == nil || !strings.Contains(err.Error(), "can not be sent again") {
+			t.Fatalf("Expected unsupported redirect error, but got %v", err)
+		}
+	})
+}
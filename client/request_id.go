@@ -0,0 +1,75 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+type requestIDContextKey struct{}
+
+// WithRequestID returns a context that requests made with it send the given X-Request-ID.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
+
+// RequestIDFromContext returns the request ID set on the context with WithRequestID, if any.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey{}).(string)
+	return requestID, ok && requestID != ""
+}
+
+// ResponseRequestID returns the request ID of a response, which is the one reported
+// by the platform, or otherwise the one the request was sent with.
+func ResponseRequestID(response *http.Response) string {
+	if response == nil {
+		return ""
+	}
+
+	if requestID := response.Header.Get(requestIDHeader); requestID != "" {
+		return requestID
+	}
+
+	if response.Request != nil {
+		return response.Request.Header.Get(requestIDHeader)
+	}
+
+	return ""
+}
+
+// RequestID returns the request ID of the request that failed with the error, if any.
+func RequestID(err error) (string, bool) {
+	var (
+		errorResponse    *ErrorResponse
+		bodyNotJSONError BodyNotJSONError
+		requestError     *RequestError
+		eos              *EOS
+	)
+
+	switch {
+	case errors.As(err, &errorResponse):
+		return errorResponse.RequestID, errorResponse.RequestID != ""
+	case errors.As(err, &bodyNotJSONError):
+		return bodyNotJSONError.RequestID, bodyNotJSONError.RequestID != ""
+	case errors.As(err, &requestError):
+		return requestError.RequestID, requestError.RequestID != ""
+	case errors.As(err, &eos):
+		return eos.RequestID, eos.RequestID != ""
+	}
+
+	return "", false
+}
+
+// requestIDFor returns the request ID to send a request with, which is taken from
+// the headers of the options, then from the context, and generated otherwise.
+func requestIDFor(ctx context.Context, options RequestOptions) (string, error) {
+	if options.Headers != nil && options.Headers.Get(requestIDHeader) != "" {
+		return options.Headers.Get(requestIDHeader), nil
+	}
+
+	if requestID, ok := RequestIDFromContext(ctx); ok {
+		return requestID, nil
+	}
+
+	return newUUID()
+}
@@ -0,0 +1,179 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientRequestID(t *testing.T) {
+	var (
+		mutex      sync.Mutex
+		requestIDs []string
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requestIDs = append(requestIDs, r.Header.Get(requestIDHeader))
+		mutex.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/error", func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requestIDs = append(requestIDs, r.Header.Get(requestIDHeader))
+		mutex.Unlock()
+
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error": "unavailable"}`))
+	})
+	mux.HandleFunc("/subscribe", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[255, 500, {}, {"error": "internal_error"}]` + "\n"))
+	})
+	mux.HandleFunc("/not-json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte(`<html>Bad Gateway</html>`))
+	})
+
+	c, closeServer := newTestClient(t, mux, Options{RetryPolicy: &RetryPolicy{
+		MaxRetries: 2,
+		Backoff:    Backoff{Initial: time.Millisecond, Max: time.Millisecond},
+	}})
+	defer closeServer()
+
+	takeRequestIDs := func() []string {
+		mutex.Lock()
+		defer mutex.Unlock()
+
+		taken := requestIDs
+		requestIDs = nil
+		return taken
+	}
+
+	t.Run("generated", func(t *testing.T) {
+		response, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/ok"})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		response.Body.Close()
+
+		sent := takeRequestIDs()
+		uuidRegexp := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+		if len(sent) != 1 || !uuidRegexp.MatchString(sent[0]) {
+			t.Fatalf("Expected a generated request ID, but got %v", sent)
+		}
+
+		if requestID := ResponseRequestID(response); requestID != sent[0] {
+			t.Fatalf("Expected response request ID %s, but got %s", sent[0], requestID)
+		}
+	})
+
+	t.Run("from context", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "context-id")
+		response, err := c.Request(ctx, RequestOptions{Method: "GET", Path: "/ok"})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		response.Body.Close()
+
+		if sent := takeRequestIDs(); len(sent) != 1 || sent[0] != "context-id" {
+			t.Fatalf("Expected request ID context-id, but got %v", sent)
+		}
+	})
+
+	t.Run("from headers", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "context-id")
+		response, err := c.Request(ctx, RequestOptions{
+			Method:  "GET",
+			Path:    "/ok",
+			Headers: &http.Header{requestIDHeader: []string{"header-id"}},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		response.Body.Close()
+
+		if sent := takeRequestIDs(); len(sent) != 1 || sent[0] != "header-id" {
+			t.Fatalf("Expected request ID header-id, but got %v", sent)
+		}
+	})
+
+	t.Run("kept across retries and set on error responses", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "retried-id")
+		_, err := c.Request(ctx, RequestOptions{Method: "GET", Path: "/error"})
+
+		var errorResponse *ErrorResponse
+		if !errors.As(err, &errorResponse) || errorResponse.RequestID != "retried-id" {
+			t.Fatalf("Expected an error response with request ID retried-id, but got %+v", err)
+		}
+
+		if sent := takeRequestIDs(); len(sent) != 3 || sent[0] != "retried-id" || sent[2] != "retried-id" {
+			t.Fatalf("Expected 3 attempts with request ID retried-id, but got %v", sent)
+		}
+
+		var platformError *PlatformError
+		if !errors.As(err, &platformError) || platformError.RequestID != "retried-id" {
+			t.Fatalf("Expected a platform error with request ID retried-id, but got %+v", err)
+		}
+
+		if !strings.Contains(platformError.Error(), "(request ID: retried-id)") {
+			t.Fatalf("Expected the platform error to show the request ID, but got %s", platformError)
+		}
+	})
+
+	t.Run("set on end of subscription errors", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "subscription-id")
+		subscription, err := c.Subscribe(ctx, RequestOptions{Path: "/subscribe"})
+		if err != nil {
+			t.Fatalf("Expected no error subscribing, but got %+v", err)
+		}
+		defer subscription.Close()
+
+		err = <-subscription.Errors()
+		if requestID, ok := RequestID(err); !ok || requestID != "subscription-id" {
+			t.Fatalf("Expected request ID subscription-id, but got %s", requestID)
+		}
+
+		var platformError *PlatformError
+		if !errors.As(err, &platformError) || platformError.RequestID != "subscription-id" {
+			t.Fatalf("Expected a platform error with request ID subscription-id, but got %+v", err)
+		}
+	})
+
+	t.Run("set on body not JSON errors", func(t *testing.T) {
+		ctx := WithRequestID(context.Background(), "not-json-id")
+		_, err := c.Request(ctx, RequestOptions{Method: "POST", Path: "/not-json"})
+
+		var bodyNotJSONError BodyNotJSONError
+		if !errors.As(err, &bodyNotJSONError) {
+			t.Fatalf("Expected a body not JSON error, but got %+v", err)
+		}
+
+		if requestID, ok := RequestID(err); !ok || requestID != "not-json-id" {
+			t.Fatalf("Expected request ID not-json-id, but got %s", requestID)
+		}
+	})
+
+	t.Run("set on transport errors", func(t *testing.T) {
+		dead := New(Options{Host: newDeadEndpoint(t).Host})
+
+		ctx := WithRequestID(context.Background(), "transport-id")
+		_, err := dead.Request(ctx, RequestOptions{Method: "GET", Path: "/ok"})
+
+		var requestError *RequestError
+		if !errors.As(err, &requestError) {
+			t.Fatalf("Expected a request error, but got %+v", err)
+		}
+
+		if requestID, ok := RequestID(err); !ok || requestID != "transport-id" {
+			t.Fatalf("Expected request ID transport-id, but got %s", requestID)
+		}
+	})
+}
@@ -0,0 +1,290 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const lastEventIDHeader = "Last-Event-ID"
+
+// ResumeOptions configures how a resumable subscription reconnects.
+type ResumeOptions struct {
+	InitialEventID string  // Optional event ID to resume from on the first connection
+	MaxRetries     int     // Maximum consecutive reconnect attempts, 0 retries forever
+	Backoff        Backoff // Delay between reconnect attempts
+
+	// OnReconnect is an optional hook called before each reconnect attempt
+	// with the attempt number and the error that ended the previous connection.
+	OnReconnect func(attempt int, err error)
+}
+
+// ResumableSubscription is a Subscription that reconnects after transport
+// errors and retryable end of subscription messages.
+//
+// Reconnections resume from the last event received, so no events are
+// lost or delivered twice. The Errors channel only receives an error once
+// the subscription can no longer be resumed.
+type ResumableSubscription interface {
+	Subscription
+	LastEventID() string
+}
+
+type resumableSubscription struct {
+	client        *client
+	options       RequestOptions
+	resumeOptions ResumeOptions
+	body          []byte // Request body sent with every connection, nil if there is none
+
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	events chan Event
+	errors chan error
+
+	mutex       sync.Mutex
+	lastEventID string
+}
+
+// SubscribeResumable opens a subscription that is resumed with
+// the Last-Event-ID header whenever the connection is lost.
+//
+// The request body, if any, is read once and sent again with every connection.
+// An error is only returned if the first connection fails with
+// an error that can not be retried.
+func (c *client) SubscribeResumable(
+	ctx context.Context,
+	options RequestOptions,
+	resumeOptions ResumeOptions,
+) (ResumableSubscription, error) {
+	var body []byte
+	if options.Body != nil {
+		var err error
+		body, err = ioutil.ReadAll(options.Body)
+		if closer, ok := options.Body.(io.Closer); ok {
+			_ = closer.Close()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &resumableSubscription{
+		client:        c,
+		options:       options,
+		resumeOptions: resumeOptions,
+		body:          body,
+		ctx:           ctx,
+		cancel:        cancel,
+		events:        make(chan Event),
+		errors:        make(chan error, 1),
+		lastEventID:   resumeOptions.InitialEventID,
+	}
+
+	subscription, err := s.connect(0)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	go s.run(subscription)
+
+	return s, nil
+}
+
+// Events returns the channel on which events are delivered.
+func (s *resumableSubscription) Events() <-chan Event {
+	return s.events
+}
+
+// Errors returns the channel on which the reason for the end
+// of the subscription is delivered.
+func (s *resumableSubscription) Errors() <-chan error {
+	return s.errors
+}
+
+// Close ends the subscription and stops any further reconnect attempts.
+func (s *resumableSubscription) Close() error {
+	s.cancel()
+	return nil
+}
+
+// LastEventID returns the ID of the last event received.
+func (s *resumableSubscription) LastEventID() string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.lastEventID
+}
+
+// connect opens the underlying subscription, retrying failed attempts.
+func (s *resumableSubscription) connect(attempt int) (Subscription, error) {
+	for {
+		subscription, err := s.client.Subscribe(s.ctx, s.requestOptions())
+		if err == nil {
+			return subscription, nil
+		}
+
+		attempt++
+		if err := s.wait(attempt, err); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// wait decides whether a failed connection should be retried and waits
+// before the next attempt. It returns the error that ends the subscription
+// if no further attempts should be made.
+func (s *resumableSubscription) wait(attempt int, err error) error {
+	if s.ctx.Err() != nil {
+		return s.ctx.Err()
+	}
+
+	if !isResumable(err) {
+		return err
+	}
+
+	if s.resumeOptions.MaxRetries > 0 && attempt > s.resumeOptions.MaxRetries {
+		return err
+	}
+
+	if s.resumeOptions.OnReconnect != nil {
+		s.resumeOptions.OnReconnect(attempt, err)
+	}
+
+	return sleep(s.ctx, s.delay(attempt, err))
+}
+
+// run forwards events and reconnects until the subscription can not be resumed.
+func (s *resumableSubscription) run(subscription Subscription) {
+	defer close(s.errors)
+	defer close(s.events)
+	defer s.cancel()
+
+	attempt := 0
+	for {
+		received, err := s.forward(subscription)
+		subscription.Close()
+		if received {
+			attempt = 0
+		}
+
+		attempt++
+		if err := s.wait(attempt, err); err != nil {
+			if s.ctx.Err() == nil {
+				s.errors <- err
+			}
+			return
+		}
+
+		subscription, err = s.connect(attempt)
+		if err != nil {
+			if s.ctx.Err() == nil {
+				s.errors <- err
+			}
+			return
+		}
+	}
+}
+
+// forward passes events from the underlying subscription until it ends,
+// reporting whether any events were received and why it ended.
+func (s *resumableSubscription) forward(subscription Subscription) (bool, error) {
+	received := false
+	events := subscription.Events()
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				err := <-subscription.Errors()
+				if err == nil {
+					err = s.ctx.Err()
+				}
+
+				return received, err
+			}
+
+			select {
+			case s.events <- event:
+			case <-s.ctx.Done():
+				return received, s.ctx.Err()
+			}
+
+			received = true
+			s.mutex.Lock()
+			s.lastEventID = event.ID
+			s.mutex.Unlock()
+		case <-s.ctx.Done():
+			return received, s.ctx.Err()
+		}
+	}
+}
+
+// requestOptions returns the options for the next connection, with a new reader
+// of the request body, setting the Last-Event-ID header if an event has been received.
+func (s *resumableSubscription) requestOptions() RequestOptions {
+	options := s.options
+	if s.body != nil {
+		options.Body = bytes.NewReader(s.body)
+	}
+
+	headers := http.Header{}
+	if options.Headers != nil {
+		for key, values := range *options.Headers {
+			headers[key] = append([]string(nil), values...)
+		}
+	}
+
+	if lastEventID := s.LastEventID(); lastEventID != "" {
+		headers.Set(lastEventIDHeader, lastEventID)
+	}
+	options.Headers = &headers
+
+	return options
+}
+
+// delay returns how long to wait before the given attempt,
+// honouring any Retry-After header sent by the platform.
+func (s *resumableSubscription) delay(attempt int, err error) time.Duration {
+	var headers http.Header
+	switch err := err.(type) {
+	case *EOS:
+		headers = err.Headers
+	case *ErrorResponse:
+		headers = err.Headers
+	}
+
+	if delay, ok := retryAfter(headers); ok {
+		return delay
+	}
+
+	return s.resumeOptions.Backoff.Delay(attempt)
+}
+
+// isResumable reports whether a subscription that ended with
+// the given error should be reconnected.
+func isResumable(err error) bool {
+	switch err := err.(type) {
+	case *EOS:
+		return isRetryableStatus(err.Status)
+	case *ErrorResponse:
+		return isRetryableStatus(err.Status)
+	case BodyNotJSONError:
+		return isRetryableStatus(err.StatusCode)
+	}
+
+	return err != nil
+}
+
+// isRetryableStatus reports whether a request that failed with
+// the given status code may succeed if made again.
+func isRetryableStatus(status int) bool {
+	return status == http.StatusRequestTimeout ||
+		status == http.StatusTooManyRequests ||
+		status >= 500
+}
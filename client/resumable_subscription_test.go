@@ -0,0 +1,169 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestClientSubscribeResumable(t *testing.T) {
+	var (
+		mutex        sync.Mutex
+		connections  int
+		lastEventIDs []string
+		bodies       []string
+	)
+
+	client, closeServer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mutex.Lock()
+		connections++
+		connection := connections
+		lastEventIDs = append(lastEventIDs, r.Header.Get(lastEventIDHeader))
+		bodies = append(bodies, string(body))
+		mutex.Unlock()
+
+		w.WriteHeader(http.StatusOK)
+		switch connection {
+		case 1:
+			w.Write([]byte(`[1, "event-1", {}, 1]` + "\n"))
+			w.Write([]byte(`[1, "event-2", {}, 2]` + "\n"))
+			// The connection drops without an EOS
+		case 2:
+			w.Write([]byte(`[1, "event-3", {}, 3]` + "\n"))
+			w.Write([]byte(`[255, 503, {"Retry-After": "0"}, {"error": "unavailable"}]` + "\n"))
+		default:
+			w.Write([]byte(`[255, 404, {}, {"error": "not_found"}]` + "\n"))
+		}
+	}), Options{Timeout: 50 * time.Millisecond})
+	defer closeServer()
+
+	var reconnects []int
+	subscription, err := client.SubscribeResumable(context.Background(), RequestOptions{
+		Path: "/subscribe",
+		// The body can only be read once
+		Body: io.MultiReader(strings.NewReader(`{"filter": "all"}`)),
+	}, ResumeOptions{
+		InitialEventID: "event-0",
+		Backoff:        Backoff{Initial: time.Millisecond},
+		OnReconnect: func(attempt int, err error) {
+			reconnects = append(reconnects, attempt)
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe with error: %+v", err)
+	}
+	defer subscription.Close()
+
+	var eventIDs []string
+	for event := range subscription.Events() {
+		eventIDs = append(eventIDs, event.ID)
+	}
+
+	if len(eventIDs) != 3 || eventIDs[0] != "event-1" || eventIDs[2] != "event-3" {
+		t.Fatalf("Expected events 1 to 3 exactly once, but got %v", eventIDs)
+	}
+
+	switch err := (<-subscription.Errors()).(type) {
+	case *EOS:
+		if err.Status != http.StatusNotFound {
+			t.Fatalf("Expected EOS status to be 404, but got %d", err.Status)
+		}
+	default:
+		t.Fatalf("Expected EOS but got %v", err)
+	}
+
+	expectedIDs := []string{"event-0", "event-2", "event-3"}
+	if len(lastEventIDs) != len(expectedIDs) {
+		t.Fatalf("Expected %d connections, but got %d", len(expectedIDs), len(lastEventIDs))
+	}
+	for i, expected := range expectedIDs {
+		if lastEventIDs[i] != expected {
+			t.Fatalf("Expected Last-Event-ID `%s` on connection %d, but got `%s`", expected, i+1, lastEventIDs[i])
+		}
+
+		if bodies[i] != `{"filter": "all"}` {
+			t.Fatalf("Expected the request body on connection %d, but got `%s`", i+1, bodies[i])
+		}
+	}
+
+	if len(reconnects) != 2 || reconnects[0] != 1 || reconnects[1] != 1 {
+		t.Fatalf("Expected two first reconnect attempts to be reported, but got %v", reconnects)
+	}
+
+	if subscription.LastEventID() != "event-3" {
+		t.Fatalf("Expected last event ID to be `event-3`, but got `%s`", subscription.LastEventID())
+	}
+}
+
+func TestClientSubscribeResumableRetries(t *testing.T) {
+	client, closeServer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "unavailable"}`))
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"error": "forbidden"}`))
+		}
+	}), Options{Timeout: 50 * time.Millisecond})
+	defer closeServer()
+
+	t.Run("Retries are bounded", func(t *testing.T) {
+		attempts := 0
+		_, err := client.SubscribeResumable(context.Background(), RequestOptions{
+			Path: "/unavailable",
+		}, ResumeOptions{
+			MaxRetries: 2,
+			Backoff:    Backoff{Initial: time.Millisecond},
+			OnReconnect: func(attempt int, err error) {
+				attempts = attempt
+			},
+		})
+
+		errorResponse, ok := err.(*ErrorResponse)
+		if !ok || errorResponse.Status != http.StatusServiceUnavailable {
+			t.Fatalf("Expected a 503 ErrorResponse, but got %v", err)
+		}
+
+		if attempts != 2 {
+			t.Fatalf("Expected 2 reconnect attempts, but got %d", attempts)
+		}
+	})
+
+	t.Run("Client errors are not retried", func(t *testing.T) {
+		_, err := client.SubscribeResumable(context.Background(), RequestOptions{
+			Path: "/forbidden",
+		}, ResumeOptions{
+			OnReconnect: func(attempt int, err error) {
+				t.Fatalf("Expected no reconnect attempts")
+			},
+		})
+
+		errorResponse, ok := err.(*ErrorResponse)
+		if !ok || errorResponse.Status != http.StatusForbidden {
+			t.Fatalf("Expected a 403 ErrorResponse, but got %v", err)
+		}
+	})
+
+	t.Run("Cancelled contexts stop reconnecting", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		_, err := client.SubscribeResumable(ctx, RequestOptions{
+			Path: "/unavailable",
+		}, ResumeOptions{
+			Backoff: Backoff{Initial: time.Millisecond},
+		})
+		if !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("Expected deadline exceeded error, but got %v", err)
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// RetryPolicy configures how requests that fail with a transport error or
+// a retryable status, such as 429 or 503, are made again.
+//
+// Requests with a non-idempotent method, such as POST, are sent with an
+// Idempotency-Key header when retries are enabled, so the platform can
+// tell retries of the same call apart from new calls. Requests whose
+// body can not be sent again are never retried.
+type RetryPolicy struct {
+	MaxRetries int     // Maximum number of retries of a request, retries are disabled if zero
+	Backoff    Backoff // Delay between attempts, the Retry-After header takes precedence
+}
+
+// RequestError is returned when a request fails without a response from the platform.
+type RequestError struct {
+	RequestID      string // Request ID the request was sent with
+	IdempotencyKey string // Idempotency key the request was sent with, if any
+	Err            error  // The underlying error
+}
+
+// Implements the Error interface.
+func (e *RequestError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *RequestError) Unwrap() error {
+	return e.Err
+}
+
+// IdempotencyKey returns the idempotency key of the request that failed with the error, if any.
+func IdempotencyKey(err error) (string, bool) {
+	var (
+		errorResponse    *ErrorResponse
+		bodyNotJSONError BodyNotJSONError
+		requestError     *RequestError
+	)
+
+	switch {
+	case errors.As(err, &errorResponse):
+		return errorResponse.IdempotencyKey, errorResponse.IdempotencyKey != ""
+	case errors.As(err, &bodyNotJSONError):
+		return bodyNotJSONError.IdempotencyKey, bodyNotJSONError.IdempotencyKey != ""
+	case errors.As(err, &requestError):
+		return requestError.IdempotencyKey, requestError.IdempotencyKey != ""
+	}
+
+	return "", false
+}
+
+// idempotencyKey returns the idempotency key to send the request with. The key of
+// the options is used if set, and one is generated for non-idempotent methods
+// when retries are enabled.
+func (p RetryPolicy) idempotencyKey(options RequestOptions) (string, error) {
+	if options.Headers != nil && options.Headers.Get(idempotencyKeyHeader) != "" {
+		return options.Headers.Get(idempotencyKeyHeader), nil
+	}
+
+	if options.IdempotencyKey != "" || p.MaxRetries <= 0 || isIdempotentMethod(options.Method) {
+		return options.IdempotencyKey, nil
+	}
+
+	return newUUID()
+}
+
+// retry reports whether a request that failed with the given error should be
+// made again, and returns the request to send for the next attempt.
+func (p RetryPolicy) retry(ctx context.Context, request *http.Request, attempt int, err error) (*http.Request, bool) {
+	if attempt >= p.MaxRetries || ctx.Err() != nil || !isRetryable(err) {
+		return nil, false
+	}
+
+	if !isIdempotentMethod(request.Method) && request.Header.Get(idempotencyKeyHeader) == "" {
+		return nil, false
+	}
+
+	retry := request.Clone(ctx)
+	if request.Body != nil && request.Body != http.NoBody {
+		if request.GetBody == nil {
+			return nil, false
+		}
+
+		body, err := request.GetBody()
+		if err != nil {
+			return nil, false
+		}
+		retry.Body = body
+	}
+
+	return retry, true
+}
+
+// delay returns how long to wait before the given retry,
+// honouring any Retry-After header sent by the platform.
+func (p RetryPolicy) delay(attempt int, err error) time.Duration {
+	var errorResponse *ErrorResponse
+	if errors.As(err, &errorResponse) {
+		if delay, ok := retryAfter(errorResponse.Headers); ok {
+			return delay
+		}
+	}
+
+	return p.Backoff.Delay(attempt)
+}
+
+// withRequestIDs records the request ID and idempotency key of a request on the error it failed with.
+func withRequestIDs(err error, requestID string, idempotencyKey string) error {
+	if err == nil || (requestID == "" && idempotencyKey == "") {
+		return err
+	}
+
+	switch e := err.(type) {
+	case *ErrorResponse:
+		if e.RequestID == "" {
+			e.RequestID = requestID
+		}
+		e.IdempotencyKey = idempotencyKey
+		return e
+	case BodyNotJSONError:
+		e.RequestID = requestID
+		e.IdempotencyKey = idempotencyKey
+		return e
+	}
+
+	return &RequestError{RequestID: requestID, IdempotencyKey: idempotencyKey, Err: err}
+}
+
+// isRetryable reports whether a request that failed with the given error may succeed if made again.
+// Network errors are retryable, whereas errors such as redirect limits and untrusted certificates are not.
+func isRetryable(err error) bool {
+	switch err := err.(type) {
+	case *ErrorResponse:
+		return isRetryableStatus(err.Status)
+	case BodyNotJSONError:
+		return isRetryableStatus(err.StatusCode)
+	}
+
+	return isNetworkError(err)
+}
+
+// isNetworkError reports whether a request failed because of the network, such as when
+// connecting fails, the connection is reset or closed early, or a timeout expires.
+//
+// Errors returned by the redirect policy and failures to verify certificates are not
+// network errors, and neither are TLS alerts sent by the host, such as when it rejects
+// the client certificate.
+func isNetworkError(err error) bool {
+	var (
+		urlError *url.Error
+		opError  *net.OpError
+		netError net.Error
+	)
+
+	if !errors.As(err, &urlError) {
+		return false
+	}
+
+	if errors.As(err, &opError) && opError.Op == "remote error" {
+		return false
+	}
+
+	// The url.Error is itself a net.Error, so only the error it wraps is checked.
+	return errors.As(urlError.Err, &netError) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
+
+// isIdempotentMethod reports whether making a request with the method
+// more than once has the same effect as making it once.
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case "", http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+
+	return false
+}
+
+// newUUID generates a random version 4 UUID.
+func newUUID() (string, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+
+	uuid[6] = uuid[6]&0x0f | 0x40
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+}
@@ -0,0 +1,302 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientRetries(t *testing.T) {
+	var (
+		mutex    sync.Mutex
+		keys     []string
+		bodies   []string
+		failures int
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/flaky", func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+
+		mutex.Lock()
+		defer mutex.Unlock()
+		keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+		bodies = append(bodies, string(body))
+
+		if failures > 0 {
+			failures--
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"error": "unavailable"}`))
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("/invalid", func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+		mutex.Unlock()
+
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid"}`))
+	})
+
+	client, closeServer := newTestClient(t, mux, Options{RetryPolicy: &RetryPolicy{
+		MaxRetries: 2,
+		Backoff:    Backoff{Initial: time.Millisecond},
+	}})
+	defer closeServer()
+
+	reset := func(failing int) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		keys, bodies, failures = nil, nil, failing
+	}
+
+	t.Run("POST is retried with the same generated key", func(t *testing.T) {
+		reset(2)
+
+		response, err := client.Request(context.Background(), RequestOptions{
+			Method: http.MethodPost,
+			Path:   "/flaky",
+			Body:   strings.NewReader("payload"),
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		response.Body.Close()
+
+		if len(keys) != 3 {
+			t.Fatalf("Expected 3 attempts, but got %d", len(keys))
+		}
+
+		uuidRegexp := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+		if !uuidRegexp.MatchString(keys[0]) || keys[1] != keys[0] || keys[2] != keys[0] {
+			t.Fatalf("Expected the same generated key on every attempt, but got %v", keys)
+		}
+
+		for _, body := range bodies {
+			if body != "payload" {
+				t.Fatalf("Expected the body to be sent on every attempt, but got %v", bodies)
+			}
+		}
+	})
+
+	t.Run("provided key is kept and exposed on errors", func(t *testing.T) {
+		reset(3)
+
+		headers := http.Header{}
+		_, err := client.Request(context.Background(), RequestOptions{
+			Method:         http.MethodPost,
+			Path:           "/flaky",
+			Headers:        &headers,
+			IdempotencyKey: "create-user-1",
+		})
+		if !errors.Is(err, ErrServiceUnavailable) {
+			t.Fatalf("Expected a service unavailable error, but got %+v", err)
+		}
+
+		if key, ok := IdempotencyKey(err); !ok || key != "create-user-1" {
+			t.Fatalf("Expected the idempotency key on the error, but got %s", key)
+		}
+
+		if len(keys) != 3 || keys[2] != "create-user-1" {
+			t.Fatalf("Expected 3 attempts with the provided key, but got %v", keys)
+		}
+
+		if headers.Get(idempotencyKeyHeader) != "" {
+			t.Fatalf("Expected the headers of the options to be left unchanged")
+		}
+	})
+
+	t.Run("GET is retried without a key", func(t *testing.T) {
+		reset(1)
+
+		response, err := client.Request(context.Background(), RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/flaky",
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		response.Body.Close()
+
+		if len(keys) != 2 || keys[0] != "" {
+			t.Fatalf("Expected 2 attempts without a key, but got %v", keys)
+		}
+	})
+
+	t.Run("client errors are not retried", func(t *testing.T) {
+		reset(0)
+
+		_, err := client.Request(context.Background(), RequestOptions{
+			Method: http.MethodPost,
+			Path:   "/invalid",
+		})
+		if !errors.Is(err, ErrBadRequest) {
+			t.Fatalf("Expected a bad request error, but got %+v", err)
+		}
+
+		if len(keys) != 1 {
+			t.Fatalf("Expected a single attempt, but got %d", len(keys))
+		}
+	})
+
+	t.Run("bodies that can not be sent again are not retried", func(t *testing.T) {
+		reset(1)
+
+		_, err := client.Request(context.Background(), RequestOptions{
+			Method: http.MethodPost,
+			Path:   "/flaky",
+			Body:   ioutil.NopCloser(strings.NewReader("payload")),
+		})
+		if !errors.Is(err, ErrServiceUnavailable) {
+			t.Fatalf("Expected a service unavailable error, but got %+v", err)
+		}
+
+		if len(keys) != 1 {
+			t.Fatalf("Expected a single attempt, but got %d", len(keys))
+		}
+	})
+}
+
+func TestClientRetriesTransportErrors(t *testing.T) {
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	uri, _ := url.Parse(server.URL)
+	server.Close()
+
+	client := New(Options{
+		Host:        uri.Host,
+		TLSConfig:   &tls.Config{InsecureSkipVerify: true},
+		RetryPolicy: &RetryPolicy{MaxRetries: 1, Backoff: Backoff{Initial: time.Millisecond}},
+	})
+
+	_, err := client.Request(context.Background(), RequestOptions{
+		Method: http.MethodPost,
+		Path:   "/users",
+	})
+
+	var requestError *RequestError
+	if !errors.As(err, &requestError) || requestError.IdempotencyKey == "" {
+		t.Fatalf("Expected a request error with an idempotency key, but got %+v", err)
+	}
+
+	var urlError *url.Error
+	if !errors.As(err, &urlError) {
+		t.Fatalf("Expected the transport error to be wrapped, but got %+v", err)
+	}
+}
+
+func TestClientDoesNotRetryPermanentFailures(t *testing.T) {
+	retryPolicy := &RetryPolicy{MaxRetries: 3, Backoff: Backoff{Initial: time.Millisecond}}
+
+	t.Run("redirect limit", func(t *testing.T) {
+		var requests int32
+		client, closeServer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			http.Redirect(w, r, "/loop", http.StatusFound)
+		}), Options{
+			RetryPolicy:    retryPolicy,
+			RedirectPolicy: &RedirectPolicy{MaxRedirects: 1},
+		})
+		defer closeServer()
+
+		_, err := client.Request(context.Background(), RequestOptions{Method: http.MethodGet, Path: "/loop"})
+		if err == nil || !strings.Contains(err.Error(), "Stopped after 1 redirects") {
+			t.Fatalf("Expected too many redirects error, but got %v", err)
+		}
+
+		if count := atomic.LoadInt32(&requests); count != 2 {
+			t.Fatalf("Expected 2 requests, but got %d", count)
+		}
+	})
+
+	t.Run("untrusted certificate", func(t *testing.T) {
+		var connections int32
+		server := httptest.NewUnstartedServer(http.NotFoundHandler())
+		server.Config.ErrorLog = log.New(ioutil.Discard, "", 0)
+		server.Config.ConnState = func(conn net.Conn, state http.ConnState) {
+			if state == http.StateNew {
+				atomic.AddInt32(&connections, 1)
+			}
+		}
+		server.StartTLS()
+		defer server.Close()
+
+		uri, err := url.Parse(server.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse server URL: %+v", err)
+		}
+
+		client := New(Options{Host: uri.Host, RetryPolicy: retryPolicy})
+		if _, err := client.Request(context.Background(), RequestOptions{Method: http.MethodGet}); err == nil {
+			t.Fatalf("Expected a certificate error")
+		}
+
+		if count := atomic.LoadInt32(&connections); count != 1 {
+			t.Fatalf("Expected 1 connection, but got %d", count)
+		}
+	})
+
+	t.Run("network errors", func(t *testing.T) {
+		for _, err := range []error{
+			&url.Error{Op: "Get", Err: &net.OpError{Op: "dial", Err: errors.New("connection refused")}},
+			&url.Error{Op: "Get", Err: io.EOF},
+		} {
+			if !isRetryable(err) {
+				t.Fatalf("Expected %v to be retryable", err)
+			}
+		}
+
+		for _, err := range []error{
+			&url.Error{Op: "Get", Err: errors.New("Stopped after 1 redirects")},
+			&url.Error{Op: "Get", Err: x509.UnknownAuthorityError{}},
+			&url.Error{Op: "Get", Err: &net.OpError{Op: "remote error", Err: errors.New("tls: bad certificate")}},
+		} {
+			if isRetryable(err) {
+				t.Fatalf("Expected %v not to be retryable", err)
+			}
+		}
+	})
+}
+
+func TestClientWithoutRetryPolicy(t *testing.T) {
+	var keys []string
+	client, closeServer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get(idempotencyKeyHeader))
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{}`))
+	}), Options{})
+	defer closeServer()
+
+	_, err := client.Request(context.Background(), RequestOptions{
+		Method: http.MethodPost,
+		Path:   "/users",
+	})
+	if err == nil {
+		t.Fatalf("Expected an error")
+	}
+
+	if len(keys) != 1 || keys[0] != "" {
+		t.Fatalf("Expected a single attempt without a key, but got %v", keys)
+	}
+
+	if _, ok := IdempotencyKey(err); ok {
+		t.Fatalf("Expected no idempotency key on the error")
+	}
+}
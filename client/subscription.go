@@ -0,0 +1,323 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// subscribeMethod is the HTTP method used to open a subscription
+// when no method is provided in the request options.
+const subscribeMethod = "SUBSCRIBE"
+
+// Message types of the elements subscription protocol.
+// Each message is a JSON array on its own line, the first element being the type.
+const (
+	messageTypeKeepAlive = 0
+	messageTypeEvent     = 1
+	messageTypeEOS       = 255
+)
+
+// Event represents a single event received on a subscription.
+type Event struct {
+	ID      string          // Event ID, used to resume a subscription
+	Headers http.Header     // Event headers
+	Body    json.RawMessage // Raw JSON body of the event
+}
+
+// EOS represents the end of subscription message sent by the platform.
+//
+// It is delivered on the Errors channel of a Subscription and carries the
+// status and information that describes why the subscription has ended.
+type EOS struct {
+	Status    int         `json:"status"`
+	Headers   http.Header `json:"headers"`
+	Info      interface{} `json:"info"`
+	RequestID string      `json:"request_id,omitempty"` // Request ID the subscription was opened with
+}
+
+// Implements the Error interface.
+func (e *EOS) Error() string {
+	return fmt.Sprintf("End of subscription: %d, %v", e.Status, e.Info)
+}
+
+// Unwrap returns the typed PlatformError that describes the end of subscription.
+func (e *EOS) Unwrap() error {
+	return newPlatformError(e.Status, e.Headers, e.Info, e.RequestID)
+}
+
+// Subscription is a long lived streaming request to the platform.
+//
+// Events are delivered on the Events channel. When the subscription ends,
+// the reason is delivered on the Errors channel and both channels are closed.
+// Calling Close ends the subscription without delivering an error.
+type Subscription interface {
+	Events() <-chan Event
+	Errors() <-chan error
+	Close() error
+}
+
+type subscription struct {
+	body      io.ReadCloser
+	cancel    context.CancelFunc
+	ctx       context.Context
+	requestID string
+
+	events chan Event
+	errors chan error
+
+	closeOnce sync.Once
+}
+
+// Subscribe opens a subscription to the platform.
+//
+// The subscription request is made with the SUBSCRIBE method, unless
+// another method is specified in the options.
+func (c *client) Subscribe(ctx context.Context, options RequestOptions) (Subscription, error) {
+	if options.Method == "" {
+		options.Method = subscribeMethod
+	}
+
+	requestID, err := requestIDFor(ctx, options)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	request, err := c.newRequest(ctx, options)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	request.Header = request.Header.Clone()
+	request.Header.Set(requestIDHeader, requestID)
+
+	request, endpoint, err := c.firstEndpoint(ctx, request)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	request, span := c.traceRequest(request)
+	bytesOut := c.metrics.countRequestBody(request)
+	start := time.Now()
+	call := c.newCall(c.streamingClient, options)
+	response, err := sendRequest(call.httpClient, request, call.dontFollowRedirect, c.maxErrorBodySize)
+	latency := time.Since(start)
+	endSpan(span, response, err)
+	c.failover.report(ctx, endpoint, err)
+
+	c.metrics.observe(ctx, request, response, err, latency, bytesOut, false)
+	c.logger.logRequest(request, response, err, latency, nil, nil)
+	if err != nil {
+		cancel()
+		return nil, withRequestIDs(err, requestID, "")
+	}
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		_ = response.Body.Close()
+		cancel()
+		return nil, fmt.Errorf("Unsupported Subscription Response: %v", response.StatusCode)
+	}
+
+	s := newSubscription(ctx, cancel, response.Body, requestID)
+	go s.run()
+
+	return s, nil
+}
+
+func newSubscription(
+	ctx context.Context,
+	cancel context.CancelFunc,
+	body io.ReadCloser,
+	requestID string,
+) *subscription {
+	return &subscription{
+		body:      body,
+		cancel:    cancel,
+		ctx:       ctx,
+		requestID: requestID,
+		events:    make(chan Event),
+		errors:    make(chan error, 1),
+	}
+}
+
+// Events returns the channel on which events are delivered.
+func (s *subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Errors returns the channel on which the reason for the end
+// of the subscription is delivered.
+func (s *subscription) Errors() <-chan error {
+	return s.errors
+}
+
+// Close ends the subscription and releases the underlying connection.
+func (s *subscription) Close() error {
+	var err error
+	s.closeOnce.Do(func() {
+		s.cancel()
+		err = s.body.Close()
+	})
+
+	return err
+}
+
+// run reads messages off the response body until the subscription ends.
+func (s *subscription) run() {
+	defer close(s.errors)
+	defer close(s.events)
+	defer s.Close()
+
+	err := readMessages(s.body, func(event Event) bool {
+		select {
+		case s.events <- event:
+			return true
+		case <-s.ctx.Done():
+			return false
+		}
+	})
+
+	var eos *EOS
+	if errors.As(err, &eos) {
+		eos.RequestID = s.requestID
+	}
+
+	// Errors caused by the subscription being closed are not reported.
+	if err != nil && s.ctx.Err() == nil {
+		s.errors <- err
+	}
+}
+
+// readMessages parses the messages of a subscription and passes each event
+// to the handler until the handler returns false, an end of subscription
+// message is received or the stream fails.
+func readMessages(body io.Reader, handle func(Event) bool) error {
+	reader := bufio.NewReader(body)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			event, eos, parseErr := parseMessage(line)
+			if parseErr != nil {
+				return parseErr
+			}
+
+			if eos != nil {
+				return eos
+			}
+
+			if event != nil && !handle(*event) {
+				return nil
+			}
+		}
+
+		if err == io.EOF {
+			return io.ErrUnexpectedEOF
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// parseMessage parses a single line of the subscription stream.
+//
+// It returns an event, an end of subscription or neither for
+// keep alive messages and blank lines.
+func parseMessage(line []byte) (*Event, *EOS, error) {
+	line = trimLine(line)
+	if len(line) == 0 {
+		return nil, nil, nil
+	}
+
+	var message []json.RawMessage
+	if err := json.Unmarshal(line, &message); err != nil {
+		return nil, nil, fmt.Errorf("Invalid subscription message: %s", err)
+	}
+
+	if len(message) == 0 {
+		return nil, nil, errors.New("Invalid subscription message: empty message")
+	}
+
+	var messageType int
+	if err := json.Unmarshal(message[0], &messageType); err != nil {
+		return nil, nil, fmt.Errorf("Invalid subscription message type: %s", err)
+	}
+
+	switch messageType {
+	case messageTypeKeepAlive:
+		return nil, nil, nil
+	case messageTypeEvent:
+		if len(message) != 4 {
+			return nil, nil, fmt.Errorf("Invalid event message: expected 4 elements, got %d", len(message))
+		}
+
+		event := &Event{Body: message[3]}
+		if err := json.Unmarshal(message[1], &event.ID); err != nil {
+			return nil, nil, fmt.Errorf("Invalid event ID: %s", err)
+		}
+
+		headers, err := parseHeaders(message[2])
+		if err != nil {
+			return nil, nil, err
+		}
+		event.Headers = headers
+
+		return event, nil, nil
+	case messageTypeEOS:
+		if len(message) != 4 {
+			return nil, nil, fmt.Errorf("Invalid EOS message: expected 4 elements, got %d", len(message))
+		}
+
+		eos := &EOS{}
+		if err := json.Unmarshal(message[1], &eos.Status); err != nil {
+			return nil, nil, fmt.Errorf("Invalid EOS status: %s", err)
+		}
+
+		headers, err := parseHeaders(message[2])
+		if err != nil {
+			return nil, nil, err
+		}
+		eos.Headers = headers
+
+		if err := json.Unmarshal(message[3], &eos.Info); err != nil {
+			return nil, nil, fmt.Errorf("Invalid EOS info: %s", err)
+		}
+
+		return nil, eos, nil
+	}
+
+	return nil, nil, fmt.Errorf("Unknown subscription message type: %d", messageType)
+}
+
+// parseHeaders converts the headers object of a message into http.Header.
+func parseHeaders(raw json.RawMessage) (http.Header, error) {
+	var headers map[string]string
+	if err := json.Unmarshal(raw, &headers); err != nil {
+		return nil, fmt.Errorf("Invalid message headers: %s", err)
+	}
+
+	result := http.Header{}
+	for key, value := range headers {
+		result.Set(key, value)
+	}
+
+	return result, nil
+}
+
+// trimLine removes trailing line endings.
+func trimLine(line []byte) []byte {
+	for len(line) > 0 && (line[len(line)-1] == '\n' || line[len(line)-1] == '\r') {
+		line = line[:len(line)-1]
+	}
+
+	return line
+}
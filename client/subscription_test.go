@@ -0,0 +1,165 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientSubscribe(t *testing.T) {
+	var receivedMethod string
+
+	client, closeServer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedMethod = r.Method
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("[0, \"keep-alive\"]\n"))
+		w.Write([]byte(`[1, "event-1", {"foo": "bar"}, {"hello": "world"}]` + "\n"))
+		w.(http.Flusher).Flush()
+
+		// Longer than the client timeout, which must not apply to subscriptions
+		time.Sleep(100 * time.Millisecond)
+		w.Write([]byte(`[1, "event-2", {}, "second"]` + "\n"))
+		w.Write([]byte(`[255, 500, {}, {"error": "internal_error"}]` + "\n"))
+	}), Options{Timeout: 50 * time.Millisecond})
+	defer closeServer()
+
+	subscription, err := client.Subscribe(context.Background(), RequestOptions{
+		Path: "/subscribe",
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe with error: %+v", err)
+	}
+	defer subscription.Close()
+
+	if receivedMethod != subscribeMethod {
+		t.Fatalf("Expected method to be %s, but got %s", subscribeMethod, receivedMethod)
+	}
+
+	var events []Event
+	for event := range subscription.Events() {
+		events = append(events, event)
+	}
+
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, but got %d", len(events))
+	}
+
+	if events[0].ID != "event-1" {
+		t.Fatalf("Expected event ID to be `event-1`, but got `%s`", events[0].ID)
+	}
+
+	if events[0].Headers.Get("foo") != "bar" {
+		t.Fatalf("Expected header `foo` to have value `bar`, but got `%s`", events[0].Headers.Get("foo"))
+	}
+
+	if string(events[0].Body) != `{"hello": "world"}` {
+		t.Fatalf("Unexpected event body `%s`", events[0].Body)
+	}
+
+	if string(events[1].Body) != `"second"` {
+		t.Fatalf("Unexpected event body `%s`", events[1].Body)
+	}
+
+	switch err := (<-subscription.Errors()).(type) {
+	case *EOS:
+		if err.Status != http.StatusInternalServerError {
+			t.Fatalf("Expected EOS status to be 500, but got %d", err.Status)
+		}
+	default:
+		t.Fatalf("Expected EOS but got %v", err)
+	}
+}
+
+func TestClientSubscribeErrors(t *testing.T) {
+	client, closeServer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/not_found":
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error": "not_found"}`))
+		case "/invalid":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte("not a message\n"))
+		case "/dropped":
+			w.WriteHeader(http.StatusOK)
+			w.Write([]byte(`[1, "event-1", {}, {}]` + "\n"))
+		}
+	}), Options{Timeout: 50 * time.Millisecond})
+	defer closeServer()
+
+	t.Run("Error responses are returned when subscribing", func(t *testing.T) {
+		_, err := client.Subscribe(context.Background(), RequestOptions{
+			Path: "/not_found",
+		})
+
+		switch err := err.(type) {
+		case *ErrorResponse:
+			if err.Status != http.StatusNotFound {
+				t.Fatalf("Expected a 404 status, but got %v", err.Status)
+			}
+		default:
+			t.Fatalf("Expected ErrorResponse but got %v", err)
+		}
+	})
+
+	t.Run("Invalid messages end the subscription", func(t *testing.T) {
+		subscription, err := client.Subscribe(context.Background(), RequestOptions{
+			Path: "/invalid",
+		})
+		if err != nil {
+			t.Fatalf("Failed to subscribe with error: %+v", err)
+		}
+
+		err = <-subscription.Errors()
+		if err == nil || !strings.Contains(err.Error(), "Invalid subscription message") {
+			t.Fatalf("Expected invalid message error, but got %v", err)
+		}
+	})
+
+	t.Run("Streams ending without EOS are reported", func(t *testing.T) {
+		subscription, err := client.Subscribe(context.Background(), RequestOptions{
+			Path: "/dropped",
+		})
+		if err != nil {
+			t.Fatalf("Failed to subscribe with error: %+v", err)
+		}
+
+		<-subscription.Events()
+		if err := <-subscription.Errors(); err == nil {
+			t.Fatalf("Expected an error when the stream ends without EOS")
+		}
+	})
+}
+
+func TestClientSubscriptionClose(t *testing.T) {
+	done := make(chan struct{})
+	client, closeServer := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+		close(done)
+	}), Options{Timeout: 50 * time.Millisecond})
+	defer closeServer()
+
+	subscription, err := client.Subscribe(context.Background(), RequestOptions{
+		Path: "/",
+	})
+	if err != nil {
+		t.Fatalf("Failed to subscribe with error: %+v", err)
+	}
+
+	if err := subscription.Close(); err != nil {
+		t.Fatalf("Expected no error when closing the subscription, but got %+v", err)
+	}
+
+	if err := <-subscription.Errors(); err != nil {
+		t.Fatalf("Expected no error after closing the subscription, but got %+v", err)
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("Expected the subscription request to be cancelled")
+	}
+}
@@ -0,0 +1,213 @@
+package client
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const publicKeyPinPrefix = "sha256/"
+
+// TLSOptions configures client certificates, trusted roots and certificate pinning.
+// They are applied on top of the TLSConfig of the client.
+type TLSOptions struct {
+	CertFile string // Optional PEM client certificate file, reloaded when it changes
+	KeyFile  string // PEM private key file of the client certificate, reloaded when it changes
+
+	// GetClientCertificate optionally provides the client certificate for each handshake,
+	// for example from a secret store. CertFile and KeyFile are ignored if it is set.
+	GetClientCertificate func(*tls.CertificateRequestInfo) (*tls.Certificate, error)
+
+	RootCAs    *x509.CertPool // Optional pool of trusted root CAs, the system pool is used if neither is set
+	RootCAFile string         // Optional PEM file of trusted root CAs, which can not be combined with RootCAs
+
+	// PinnedPublicKeys optionally lists the SHA-256 hashes of the public keys that
+	// platform certificates must use, in the format returned by PublicKeyPin.
+	// A connection is accepted if any certificate of its chain matches a pin.
+	PinnedPublicKeys []string
+	PinnedHosts      []string // Hosts that pins apply to, defaults to every host
+}
+
+// PublicKeyPin returns the pin of the public key of a certificate, which is
+// the base64 encoded SHA-256 hash of its SubjectPublicKeyInfo prefixed with sha256/.
+func PublicKeyPin(certificate *x509.Certificate) string {
+	hash := sha256.Sum256(certificate.RawSubjectPublicKeyInfo)
+	return publicKeyPinPrefix + base64.StdEncoding.EncodeToString(hash[:])
+}
+
+// config returns a copy of the base config with the options applied.
+func (o TLSOptions) config(base *tls.Config) (*tls.Config, error) {
+	config := &tls.Config{}
+	if base != nil {
+		config = base.Clone()
+	}
+
+	if o.GetClientCertificate != nil {
+		config.GetClientCertificate = o.GetClientCertificate
+	} else if o.CertFile != "" || o.KeyFile != "" {
+		reloader, err := newCertificateReloader(o.CertFile, o.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = reloader.getClientCertificate
+	}
+
+	if o.RootCAs != nil && o.RootCAFile != "" {
+		return nil, errors.New("Only one of RootCAs and RootCAFile may be provided")
+	}
+
+	if o.RootCAs != nil {
+		config.RootCAs = o.RootCAs
+	}
+
+	// The certificates of the file are added to a new pool, so no pool of the caller is modified.
+	if o.RootCAFile != "" {
+		pem, err := ioutil.ReadFile(o.RootCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to read root CA file: %s", err)
+		}
+
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("No certificates found in root CA file: %s", o.RootCAFile)
+		}
+	}
+
+	if len(o.PinnedPublicKeys) > 0 {
+		verifyPins, err := newPinVerifier(o.PinnedPublicKeys, o.PinnedHosts)
+		if err != nil {
+			return nil, err
+		}
+
+		verifyConnection := config.VerifyConnection
+		config.VerifyConnection = func(state tls.ConnectionState) error {
+			if verifyConnection != nil {
+				if err := verifyConnection(state); err != nil {
+					return err
+				}
+			}
+
+			return verifyPins(state)
+		}
+	}
+
+	return config, nil
+}
+
+// newPinVerifier returns a function verifying that connections to the pinned hosts
+// use a certificate chain with a pinned public key.
+func newPinVerifier(pins []string, hosts []string) (func(tls.ConnectionState) error, error) {
+	pinned := map[string]bool{}
+	for _, pin := range pins {
+		hash, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, publicKeyPinPrefix))
+		if err != nil || len(hash) != sha256.Size {
+			return nil, fmt.Errorf("Invalid public key pin: %s", pin)
+		}
+		pinned[publicKeyPinPrefix+base64.StdEncoding.EncodeToString(hash)] = true
+	}
+
+	pinnedHosts := map[string]bool{}
+	for _, host := range hosts {
+		pinnedHosts[strings.ToLower(host)] = true
+	}
+
+	return func(state tls.ConnectionState) error {
+		if len(pinnedHosts) > 0 && !pinnedHosts[strings.ToLower(state.ServerName)] {
+			return nil
+		}
+
+		// Without verified chains, such as when verification is skipped,
+		// only the certificate presented by the server is trusted.
+		certificates := state.PeerCertificates
+		if len(certificates) > 1 {
+			certificates = certificates[:1]
+		}
+		for _, chain := range state.VerifiedChains {
+			certificates = append(certificates, chain...)
+		}
+
+		for _, certificate := range certificates {
+			if pinned[PublicKeyPin(certificate)] {
+				return nil
+			}
+		}
+
+		return fmt.Errorf("Certificate of %s does not match any pinned public key", state.ServerName)
+	}, nil
+}
+
+// certificateReloader loads a client certificate from files, reloading it when they change.
+type certificateReloader struct {
+	certFile string
+	keyFile  string
+
+	mutex       sync.Mutex
+	certificate *tls.Certificate
+	version     string // Modification times and sizes of the loaded files
+}
+
+// newCertificateReloader loads the certificate, failing if the files are invalid.
+func newCertificateReloader(certFile string, keyFile string) (*certificateReloader, error) {
+	if certFile == "" || keyFile == "" {
+		return nil, errors.New("Both a certificate file and a key file must be provided")
+	}
+
+	r := &certificateReloader{certFile: certFile, keyFile: keyFile}
+	if _, err := r.getClientCertificate(nil); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// getClientCertificate returns the certificate, reloading it if the files changed.
+// The previous certificate is kept if reloading fails, for example while the
+// files are being replaced.
+func (r *certificateReloader) getClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	version, err := r.fileVersion()
+	if err == nil && version == r.version {
+		return r.certificate, nil
+	}
+
+	if err == nil {
+		var certificate tls.Certificate
+		certificate, err = tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err == nil {
+			r.certificate = &certificate
+			r.version = version
+			return r.certificate, nil
+		}
+	}
+
+	if r.certificate != nil {
+		return r.certificate, nil
+	}
+
+	return nil, fmt.Errorf("Failed to load client certificate: %s", err)
+}
+
+// fileVersion identifies the current content of the certificate and key files.
+func (r *certificateReloader) fileVersion() (string, error) {
+	version := ""
+	for _, path := range []string{r.certFile, r.keyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+
+		version += fmt.Sprintf("%s:%d;", info.ModTime().Format(time.RFC3339Nano), info.Size())
+	}
+
+	return version, nil
+}
@@ -0,0 +1,258 @@
+package client
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testCertificate struct {
+	certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// newTestCertificate issues a certificate signed by the parent, or a self-signed CA if nil.
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate) *testCertificate {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %+v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+
+	issuer, issuerKey := template, key
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+	} else {
+		issuer, issuerKey = parent.certificate, parent.key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, issuer, &key.PublicKey, issuerKey)
+	if err != nil {
+		t.Fatalf("Failed to create certificate: %+v", err)
+	}
+
+	certificate, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("Failed to parse certificate: %+v", err)
+	}
+
+	return &testCertificate{certificate, key}
+}
+
+// write writes the certificate and key as PEM files.
+func (c *testCertificate) write(t *testing.T, certFile string, keyFile string) {
+	keyDER, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		t.Fatalf("Failed to marshal key: %+v", err)
+	}
+
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.certificate.Raw})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	if err := ioutil.WriteFile(certFile, certPEM, 0600); err != nil {
+		t.Fatalf("Failed to write certificate: %+v", err)
+	}
+	if err := ioutil.WriteFile(keyFile, keyPEM, 0600); err != nil {
+		t.Fatalf("Failed to write key: %+v", err)
+	}
+}
+
+func TestClientTLSOptions(t *testing.T) {
+	ca := newTestCertificate(t, "test-ca", nil)
+	caPool := x509.NewCertPool()
+	caPool.AddCert(ca.certificate)
+
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.TLS.PeerCertificates[0].Subject.CommonName))
+	}))
+	server.TLS = &tls.Config{
+		ClientAuth: tls.RequireAndVerifyClientCert,
+		ClientCAs:  caPool,
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %+v", err)
+	}
+
+	serverPool := x509.NewCertPool()
+	serverPool.AddCert(server.Certificate())
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	newTestCertificate(t, "client-1", ca).write(t, certFile, keyFile)
+
+	request := func(c Client) (string, error) {
+		response, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/"})
+		if err != nil {
+			return "", err
+		}
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+		return string(body), err
+	}
+
+	t.Run("certificate files are reloaded when they change", func(t *testing.T) {
+		c := New(Options{
+			Host: uri.Host,
+			TLS: &TLSOptions{
+				CertFile: certFile,
+				KeyFile:  keyFile,
+				RootCAs:  serverPool,
+			},
+			TransportOptions: TransportOptions{IdleConnTimeout: time.Millisecond},
+		})
+
+		subject, err := request(c)
+		if err != nil || subject != "client-1" {
+			t.Fatalf("Expected the client-1 certificate, but got %q and error %+v", subject, err)
+		}
+
+		newTestCertificate(t, "client-2", ca).write(t, certFile, keyFile)
+		later := time.Now().Add(time.Minute)
+		os.Chtimes(certFile, later, later)
+		os.Chtimes(keyFile, later, later)
+		time.Sleep(10 * time.Millisecond)
+
+		subject, err = request(c)
+		if err != nil || subject != "client-2" {
+			t.Fatalf("Expected the client-2 certificate, but got %q and error %+v", subject, err)
+		}
+	})
+
+	t.Run("certificate provider", func(t *testing.T) {
+		certificate := newTestCertificate(t, "provided", ca)
+		c := New(Options{
+			Host: uri.Host,
+			TLS: &TLSOptions{
+				GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+					return &tls.Certificate{
+						Certificate: [][]byte{certificate.certificate.Raw},
+						PrivateKey:  certificate.key,
+					}, nil
+				},
+				RootCAs: serverPool,
+			},
+		})
+
+		subject, err := request(c)
+		if err != nil || subject != "provided" {
+			t.Fatalf("Expected the provided certificate, but got %q and error %+v", subject, err)
+		}
+	})
+
+	t.Run("root CA file", func(t *testing.T) {
+		rootCAFile := filepath.Join(dir, "root.crt")
+		rootCAPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+		if err := ioutil.WriteFile(rootCAFile, rootCAPEM, 0600); err != nil {
+			t.Fatalf("Failed to write root CA file: %+v", err)
+		}
+
+		c := New(Options{
+			Host: uri.Host,
+			TLS:  &TLSOptions{CertFile: certFile, KeyFile: keyFile, RootCAFile: rootCAFile},
+		})
+
+		if _, err := request(c); err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+
+		_, err := TLSOptions{RootCAs: serverPool, RootCAFile: rootCAFile}.config(nil)
+		if err == nil || err.Error() != "Only one of RootCAs and RootCAFile may be provided" {
+			t.Fatalf("Expected an error, but got %+v", err)
+		}
+	})
+
+	t.Run("invalid certificate files", func(t *testing.T) {
+		c := New(Options{
+			Host: uri.Host,
+			TLS:  &TLSOptions{CertFile: filepath.Join(dir, "missing.crt"), KeyFile: keyFile},
+		})
+
+		_, err := request(c)
+		if err == nil || !strings.HasPrefix(err.Error(), "Failed to load client certificate") {
+			t.Fatalf("Expected a certificate load error, but got %+v", err)
+		}
+	})
+
+	t.Run("pinned public keys", func(t *testing.T) {
+		pinned := New(Options{
+			Host: uri.Host,
+			TLS: &TLSOptions{
+				CertFile:         certFile,
+				KeyFile:          keyFile,
+				RootCAs:          serverPool,
+				PinnedPublicKeys: []string{PublicKeyPin(server.Certificate())},
+			},
+		})
+
+		if _, err := request(pinned); err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+
+		mismatched := New(Options{
+			Host: uri.Host,
+			TLS: &TLSOptions{
+				CertFile:         certFile,
+				KeyFile:          keyFile,
+				RootCAs:          serverPool,
+				PinnedPublicKeys: []string{PublicKeyPin(ca.certificate)},
+			},
+		})
+
+		_, err := request(mismatched)
+		if err == nil || !strings.Contains(err.Error(), "does not match any pinned public key") {
+			t.Fatalf("Expected a pinning error, but got %+v", err)
+		}
+
+		otherHost := New(Options{
+			Host: uri.Host,
+			TLS: &TLSOptions{
+				CertFile:         certFile,
+				KeyFile:          keyFile,
+				RootCAs:          serverPool,
+				PinnedPublicKeys: []string{PublicKeyPin(ca.certificate)},
+				PinnedHosts:      []string{"us1.pusherplatform.io"},
+			},
+		})
+
+		if _, err := request(otherHost); err != nil {
+			t.Fatalf("Expected pins of other hosts to be ignored, but got %+v", err)
+		}
+	})
+
+	t.Run("invalid pin", func(t *testing.T) {
+		_, err := TLSOptions{PinnedPublicKeys: []string{"sha256/invalid"}}.config(nil)
+		if err == nil || err.Error() != "Invalid public key pin: sha256/invalid" {
+			t.Fatalf("Expected an invalid pin error, but got %+v", err)
+		}
+	})
+}
@@ -0,0 +1,87 @@
+package client
+
+import (
+	"crypto/tls"
+	"errors"
+	"net/http"
+	"net/http/httptrace"
+	"time"
+
+	"github.com/pusher/pusher-platform-go/tracing"
+)
+
+const requestSpanName = "pusher_platform.request"
+
+// traceRequest starts a span for the request, propagates the trace context
+// with the traceparent and tracestate headers and records connection timings.
+func (c *client) traceRequest(request *http.Request) (*http.Request, tracing.Span) {
+	ctx, span := c.tracer.Start(request.Context(), requestSpanName)
+	span.SetAttribute("http.method", request.Method)
+	span.SetAttribute("http.host", request.URL.Host)
+	span.SetAttribute("http.path", request.URL.Path)
+
+	spanContext := span.SpanContext()
+	if !spanContext.IsValid() {
+		spanContext = tracing.SpanContextFromContext(request.Context())
+	}
+	tracing.Inject(request.Header, spanContext)
+
+	if _, ok := c.tracer.(tracing.NoopTracer); !ok {
+		ctx = httptrace.WithClientTrace(ctx, clientTrace(span))
+	}
+
+	return request.WithContext(ctx), span
+}
+
+// clientTrace records the timings of DNS lookups, connections,
+// TLS handshakes and the first response byte as span events.
+func clientTrace(span tracing.Span) *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			span.AddEvent("dns.start", time.Now())
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			span.AddEvent("dns.done", time.Now())
+		},
+		ConnectStart: func(network, addr string) {
+			span.AddEvent("connect.start", time.Now())
+		},
+		ConnectDone: func(network, addr string, err error) {
+			span.AddEvent("connect.done", time.Now())
+		},
+		TLSHandshakeStart: func() {
+			span.AddEvent("tls.start", time.Now())
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			span.AddEvent("tls.done", time.Now())
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			span.SetAttribute("http.connection_reused", info.Reused)
+		},
+		GotFirstResponseByte: func() {
+			span.AddEvent("first_byte", time.Now())
+		},
+	}
+}
+
+// endSpan records the outcome of a request and ends its span.
+// Platform errors mark the span with their error type.
+func endSpan(span tracing.Span, response *http.Response, err error) {
+	if status := responseStatus(response, err); status != 0 {
+		span.SetAttribute("http.status_code", status)
+	}
+
+	if err != nil {
+		span.RecordError(err)
+
+		var platformError *PlatformError
+		if errors.As(err, &platformError) {
+			span.SetAttribute("platform.error_type", platformError.ErrorType)
+			if platformError.RequestID != "" {
+				span.SetAttribute("platform.request_id", platformError.RequestID)
+			}
+		}
+	}
+
+	span.End()
+}
@@ -0,0 +1,166 @@
+package client
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pusher/pusher-platform-go/tracing"
+)
+
+type testSpan struct {
+	mutex       sync.Mutex
+	name        string
+	attributes  map[string]interface{}
+	events      []string
+	err         error
+	ended       bool
+	spanContext tracing.SpanContext
+}
+
+func (s *testSpan) SetAttribute(key string, value interface{}) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.attributes[key] = value
+}
+
+func (s *testSpan) AddEvent(name string, timestamp time.Time) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.events = append(s.events, name)
+}
+
+func (s *testSpan) RecordError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.err = err
+}
+
+func (s *testSpan) SpanContext() tracing.SpanContext {
+	return s.spanContext
+}
+
+func (s *testSpan) End() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.ended = true
+}
+
+type testTracer struct {
+	spans []*testSpan
+}
+
+func (t *testTracer) Start(ctx context.Context, name string) (context.Context, tracing.Span) {
+	parent := tracing.SpanContextFromContext(ctx)
+	span := &testSpan{name: name, attributes: map[string]interface{}{}}
+	span.spanContext = parent
+	rand.Read(span.spanContext.SpanID[:])
+	t.spans = append(t.spans, span)
+
+	return tracing.ContextWithSpanContext(ctx, span.spanContext), span
+}
+
+func TestClientTracing(t *testing.T) {
+	var receivedTraceParent, receivedTraceState string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		receivedTraceParent = r.Header.Get(tracing.TraceParentHeader)
+		receivedTraceState = r.Header.Get(tracing.TraceStateHeader)
+		w.WriteHeader(http.StatusOK)
+	})
+	mux.HandleFunc("/missing", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(requestIDHeader, "request-id")
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": "services/not_found"}`))
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server url with error: %+v", err)
+	}
+
+	parent, _ := tracing.ParseTraceParent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	parent.TraceState = "vendor=value"
+	ctx := tracing.ContextWithSpanContext(context.Background(), parent)
+
+	t.Run("Spans are started and propagated", func(t *testing.T) {
+		tracer := &testTracer{}
+		client := New(Options{
+			Host: uri.Host,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			Tracer: tracer,
+		})
+
+		_, err := client.Request(ctx, RequestOptions{Method: http.MethodGet, Path: "/ok"})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		if len(tracer.spans) != 1 {
+			t.Fatalf("Expected a span to be started, but got %d", len(tracer.spans))
+		}
+
+		span := tracer.spans[0]
+		if receivedTraceParent != span.spanContext.TraceParent() || receivedTraceState != "vendor=value" {
+			t.Fatalf("Expected the span context to be propagated, but got `%s` `%s`", receivedTraceParent, receivedTraceState)
+		}
+
+		if !span.ended || span.attributes["http.status_code"] != http.StatusOK || span.attributes["http.path"] != "/ok" {
+			t.Fatalf("Unexpected span %+v", span)
+		}
+
+		events := map[string]bool{}
+		for _, event := range span.events {
+			events[event] = true
+		}
+		for _, event := range []string{"connect.start", "connect.done", "tls.start", "tls.done", "first_byte"} {
+			if !events[event] {
+				t.Fatalf("Expected span to have event `%s`, but got %v", event, span.events)
+			}
+		}
+
+		_, err = client.Request(ctx, RequestOptions{Method: http.MethodGet, Path: "/missing"})
+		if err == nil {
+			t.Fatalf("Expected an error response")
+		}
+
+		span = tracer.spans[1]
+		if span.err == nil || span.attributes["platform.error_type"] != "services/not_found" {
+			t.Fatalf("Expected span to be marked with the platform error, but got %+v", span)
+		}
+
+		if span.attributes["platform.request_id"] != "request-id" {
+			t.Fatalf("Expected span to have the request ID, but got %v", span.attributes["platform.request_id"])
+		}
+	})
+
+	t.Run("Trace context is propagated without a tracer", func(t *testing.T) {
+		client := New(Options{
+			Host: uri.Host,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+		})
+
+		_, err := client.Request(ctx, RequestOptions{Method: http.MethodGet, Path: "/ok"})
+		if err != nil {
+			t.Fatalf("Failed to request backend resource with error: %+v", err)
+		}
+
+		if receivedTraceParent != parent.TraceParent() {
+			t.Fatalf("Expected traceparent `%s`, but got `%s`", parent.TraceParent(), receivedTraceParent)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Defaults of the transport built by a client, which match those of http.DefaultTransport.
+const (
+	defaultMaxIdleConns          = 100
+	defaultIdleConnTimeout       = 90 * time.Second
+	defaultDialTimeout           = 30 * time.Second
+	defaultKeepAlive             = 30 * time.Second
+	defaultTLSHandshakeTimeout   = 10 * time.Second
+	defaultExpectContinueTimeout = time.Second
+	defaultHTTP2PingTimeout      = 15 * time.Second
+)
+
+// TransportOptions configures the transport a client builds when no Transport is provided.
+// Zero values use the defaults of http.DefaultTransport.
+type TransportOptions struct {
+	MaxIdleConns          int           // Maximum idle connections across all hosts, defaults to 100
+	MaxIdleConnsPerHost   int           // Maximum idle connections to each host, defaults to 2
+	MaxConnsPerHost       int           // Maximum connections to each host, unlimited if zero
+	IdleConnTimeout       time.Duration // Time idle connections are kept open, defaults to 90s
+	DialTimeout           time.Duration // Time to wait for a connection to be established, defaults to 30s
+	KeepAlive             time.Duration // Interval of TCP keep-alive probes, defaults to 30s
+	TLSHandshakeTimeout   time.Duration // Time to wait for a TLS handshake, defaults to 10s
+	ResponseHeaderTimeout time.Duration // Time to wait for response headers once a request is sent, unlimited if zero
+	ExpectContinueTimeout time.Duration // Time to wait for a 100-continue response, defaults to 1s
+
+	// Proxy returns the proxy to send a request through, or a nil URL to connect directly.
+	// Defaults to http.ProxyFromEnvironment, unless UnixSocket is set.
+	Proxy func(*http.Request) (*url.URL, error)
+
+	// DialContext optionally opens the connections of requests, for example through a
+	// service mesh. DialTimeout and KeepAlive are ignored if it is set.
+	DialContext func(ctx context.Context, network string, address string) (net.Conn, error)
+
+	// UnixSocket optionally sends every request over the Unix domain socket at the given
+	// path, such as that of a sidecar proxy. The Host header and TLS server name
+	// remain those of the platform host. DialContext is ignored if it is set.
+	UnixSocket string
+
+	// HTTP2 enables HTTP/2, including when a TLSConfig is given, which otherwise limits requests to HTTP/1.1.
+	HTTP2 *HTTP2Options
+}
+
+// HTTP2Options configures HTTP/2 connections.
+//
+// Ping health checks require Go 1.24 or later. Clients built with earlier
+// versions of Go fail every request if a ping health check is configured.
+type HTTP2Options struct {
+	ReadIdleTimeout time.Duration // Time without frames after which a ping health check is sent, disabled if zero
+	PingTimeout     time.Duration // Time to wait for a ping response before closing the connection, defaults to 15s
+}
+
+// validate reports whether the options are supported by the version of Go.
+func (o HTTP2Options) validate() error {
+	if !http2PingHealthChecks && (o.ReadIdleTimeout > 0 || o.PingTimeout > 0) {
+		return errors.New("HTTP/2 ping health checks require Go 1.24 or later")
+	}
+
+	return nil
+}
+
+// newTransport builds the transport of a client.
+//
+// HTTP/2 is attempted by default unless a TLS config is given, as with
+// the default transport that was previously used without one.
+func newTransport(tlsConfig *tls.Config, options TransportOptions) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   durationOrDefault(options.DialTimeout, defaultDialTimeout),
+		KeepAlive: durationOrDefault(options.KeepAlive, defaultKeepAlive),
+	}
+
+	dialContext := dialer.DialContext
+	if options.DialContext != nil {
+		dialContext = options.DialContext
+	}
+
+	proxy := options.Proxy
+	if proxy == nil && options.UnixSocket == "" {
+		proxy = http.ProxyFromEnvironment
+	}
+
+	if options.UnixSocket != "" {
+		socket := options.UnixSocket
+		dialContext = func(ctx context.Context, network string, address string) (net.Conn, error) {
+			return dialer.DialContext(ctx, "unix", socket)
+		}
+	}
+
+	maxIdleConns := options.MaxIdleConns
+	if maxIdleConns == 0 {
+		maxIdleConns = defaultMaxIdleConns
+	}
+
+	transport := &http.Transport{
+		Proxy:                 proxy,
+		DialContext:           dialContext,
+		TLSClientConfig:       tlsConfig,
+		MaxIdleConns:          maxIdleConns,
+		MaxIdleConnsPerHost:   options.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       options.MaxConnsPerHost,
+		IdleConnTimeout:       durationOrDefault(options.IdleConnTimeout, defaultIdleConnTimeout),
+		TLSHandshakeTimeout:   durationOrDefault(options.TLSHandshakeTimeout, defaultTLSHandshakeTimeout),
+		ResponseHeaderTimeout: options.ResponseHeaderTimeout,
+		ExpectContinueTimeout: durationOrDefault(options.ExpectContinueTimeout, defaultExpectContinueTimeout),
+		ForceAttemptHTTP2:     tlsConfig == nil || options.HTTP2 != nil,
+		DisableCompression:    true,
+	}
+
+	if options.HTTP2 != nil {
+		configureHTTP2(transport, *options.HTTP2)
+	}
+
+	return transport
+}
+
+// durationOrDefault returns the duration, or the default if it is not positive.
+func durationOrDefault(duration time.Duration, defaultDuration time.Duration) time.Duration {
+	if duration <= 0 {
+		return defaultDuration
+	}
+
+	return duration
+}
@@ -0,0 +1,20 @@
+//go:build go1.24
+
+package client
+
+import "net/http"
+
+// http2PingHealthChecks reports whether ping health checks of HTTP/2 connections are supported.
+const http2PingHealthChecks = true
+
+// configureHTTP2 enables ping health checks of HTTP/2 connections.
+func configureHTTP2(transport *http.Transport, options HTTP2Options) {
+	if options.ReadIdleTimeout <= 0 {
+		return
+	}
+
+	transport.HTTP2 = &http.HTTP2Config{
+		SendPingTimeout: options.ReadIdleTimeout,
+		PingTimeout:     durationOrDefault(options.PingTimeout, defaultHTTP2PingTimeout),
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !go1.24
+
+package client
+
+import "net/http"
+
+// http2PingHealthChecks reports whether ping health checks of HTTP/2 connections are supported.
+const http2PingHealthChecks = false
+
+// configureHTTP2 does nothing, since ping health checks require Go 1.24.
+func configureHTTP2(transport *http.Transport, options HTTP2Options) {}
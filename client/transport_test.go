@@ -0,0 +1,277 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNewTransport(t *testing.T) {
+	t.Run("defaults", func(t *testing.T) {
+		transport := newTransport(nil, TransportOptions{})
+
+		if transport.MaxIdleConns != defaultMaxIdleConns {
+			t.Fatalf("Expected %d max idle connections, but got %d", defaultMaxIdleConns, transport.MaxIdleConns)
+		}
+
+		if transport.IdleConnTimeout != defaultIdleConnTimeout {
+			t.Fatalf("Expected idle timeout %s, but got %s", defaultIdleConnTimeout, transport.IdleConnTimeout)
+		}
+
+		if transport.TLSHandshakeTimeout != defaultTLSHandshakeTimeout {
+			t.Fatalf("Expected TLS handshake timeout %s, but got %s", defaultTLSHandshakeTimeout, transport.TLSHandshakeTimeout)
+		}
+
+		if !transport.ForceAttemptHTTP2 {
+			t.Fatalf("Expected HTTP/2 to be attempted without a TLS config")
+		}
+	})
+
+	t.Run("options", func(t *testing.T) {
+		transport := newTransport(&tls.Config{}, TransportOptions{
+			MaxIdleConns:          10,
+			MaxIdleConnsPerHost:   5,
+			MaxConnsPerHost:       20,
+			IdleConnTimeout:       time.Minute,
+			TLSHandshakeTimeout:   time.Second,
+			ResponseHeaderTimeout: 2 * time.Second,
+		})
+
+		if transport.MaxIdleConns != 10 || transport.MaxIdleConnsPerHost != 5 || transport.MaxConnsPerHost != 20 {
+			t.Fatalf("Expected the pool sizes to be set, but got %+v", transport)
+		}
+
+		if transport.IdleConnTimeout != time.Minute ||
+			transport.TLSHandshakeTimeout != time.Second ||
+			transport.ResponseHeaderTimeout != 2*time.Second {
+			t.Fatalf("Expected the timeouts to be set, but got %+v", transport)
+		}
+
+		if transport.ForceAttemptHTTP2 {
+			t.Fatalf("Expected HTTP/2 not to be attempted with a TLS config")
+		}
+	})
+}
+
+func TestClientTransportOptions(t *testing.T) {
+	t.Run("timeout without a TLS config", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-time.After(time.Second):
+			case <-r.Context().Done():
+			}
+		}))
+		defer server.Close()
+
+		uri, err := url.Parse(server.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse server URL: %+v", err)
+		}
+
+		c := New(Options{
+			Endpoint: &Endpoint{Scheme: "http", Host: uri.Host},
+			Timeout:  50 * time.Millisecond,
+		})
+
+		_, err = c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/slow"})
+		var timeoutError interface{ Timeout() bool }
+		if !errors.As(err, &timeoutError) || !timeoutError.Timeout() {
+			t.Fatalf("Expected a timeout error, but got %+v", err)
+		}
+	})
+
+	t.Run("HTTP/2 with a TLS config", func(t *testing.T) {
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Proto))
+		}))
+		server.EnableHTTP2 = true
+		server.StartTLS()
+		defer server.Close()
+
+		uri, err := url.Parse(server.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse server URL: %+v", err)
+		}
+
+		c := New(Options{
+			Host: uri.Host,
+			TLSConfig: &tls.Config{
+				InsecureSkipVerify: true,
+			},
+			TransportOptions: TransportOptions{
+				HTTP2: &HTTP2Options{},
+			},
+		})
+
+		response, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/"})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		defer response.Body.Close()
+
+		if response.ProtoMajor != 2 {
+			t.Fatalf("Expected an HTTP/2 response, but got %s", response.Proto)
+		}
+	})
+
+	t.Run("HTTP/2 ping health checks", func(t *testing.T) {
+		options := Options{TransportOptions: TransportOptions{
+			HTTP2: &HTTP2Options{ReadIdleTimeout: time.Minute},
+		}}
+
+		err := options.Validate()
+		if http2PingHealthChecks && err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		if !http2PingHealthChecks && err == nil {
+			t.Fatalf("Expected an error since ping health checks are not supported")
+		}
+	})
+
+	t.Run("proxy", func(t *testing.T) {
+		proxied := make(chan string, 1)
+		proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			proxied <- r.URL.String()
+			w.WriteHeader(http.StatusOK)
+		}))
+		defer proxy.Close()
+
+		proxyURL, err := url.Parse(proxy.URL)
+		if err != nil {
+			t.Fatalf("Failed to parse proxy URL: %+v", err)
+		}
+
+		c := New(Options{
+			Endpoint: &Endpoint{Scheme: "http", Host: "platform.example.com"},
+			TransportOptions: TransportOptions{
+				Proxy: http.ProxyURL(proxyURL),
+			},
+		})
+
+		response, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/users"})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		response.Body.Close()
+
+		if target := <-proxied; target != "http://platform.example.com/users" {
+			t.Fatalf("Expected the request to be proxied, but got %s", target)
+		}
+	})
+}
+
+func TestClientDialer(t *testing.T) {
+	// Socket paths are limited in length, so t.TempDir is too long on some systems.
+	dir, err := os.MkdirTemp("", "platform")
+	if err != nil {
+		t.Fatalf("Failed to create directory: %+v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	newSocketServer := func(t *testing.T, socket string) *httptest.Server {
+		listener, err := net.Listen("unix", socket)
+		if err != nil {
+			t.Fatalf("Failed to listen: %+v", err)
+		}
+
+		server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serverName := ""
+			if r.TLS != nil {
+				serverName = r.TLS.ServerName
+			}
+			w.Write([]byte(r.Host + " " + serverName))
+		}))
+		server.Listener.Close()
+		server.Listener = listener
+		return server
+	}
+
+	request := func(t *testing.T, c Client) string {
+		response, err := c.Request(context.Background(), RequestOptions{Method: "GET", Path: "/"})
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+		defer response.Body.Close()
+
+		body, err := ioutil.ReadAll(response.Body)
+		if err != nil {
+			t.Fatalf("Failed to read body: %+v", err)
+		}
+		return string(body)
+	}
+
+	t.Run("unix socket", func(t *testing.T) {
+		server := newSocketServer(t, filepath.Join(dir, "plain.sock"))
+		server.Start()
+		defer server.Close()
+
+		c := New(Options{
+			Endpoint: &Endpoint{Scheme: "http", Host: "platform.example.com"},
+			TransportOptions: TransportOptions{
+				UnixSocket: filepath.Join(dir, "plain.sock"),
+			},
+		})
+
+		if body := request(t, c); body != "platform.example.com " {
+			t.Fatalf("Expected the platform host, but got %q", body)
+		}
+	})
+
+	t.Run("unix socket with TLS", func(t *testing.T) {
+		server := newSocketServer(t, filepath.Join(dir, "tls.sock"))
+		server.StartTLS()
+		defer server.Close()
+
+		// The certificate of test servers is valid for example.com.
+		roots := x509.NewCertPool()
+		roots.AddCert(server.Certificate())
+
+		c := New(Options{
+			Host:      "example.com",
+			TLSConfig: &tls.Config{RootCAs: roots},
+			TransportOptions: TransportOptions{
+				UnixSocket: filepath.Join(dir, "tls.sock"),
+			},
+		})
+
+		if body := request(t, c); body != "example.com example.com" {
+			t.Fatalf("Expected the platform host and server name, but got %q", body)
+		}
+	})
+
+	t.Run("custom dialer", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(r.Host))
+		}))
+		defer server.Close()
+
+		dialed := make(chan string, 1)
+		c := New(Options{
+			Endpoint: &Endpoint{Scheme: "http", Host: "platform.example.com"},
+			TransportOptions: TransportOptions{
+				DialContext: func(ctx context.Context, network string, address string) (net.Conn, error) {
+					dialed <- address
+					return (&net.Dialer{}).DialContext(ctx, network, server.Listener.Addr().String())
+				},
+			},
+		})
+
+		if body := request(t, c); body != "platform.example.com" {
+			t.Fatalf("Expected the platform host, but got %q", body)
+		}
+
+		if address := <-dialed; address != "platform.example.com:80" {
+			t.Fatalf("Expected platform.example.com:80 to be dialed, but got %s", address)
+		}
+	})
+}
@@ -7,6 +7,9 @@ import (
 	"net/http"
 	"net/url"
 	"time"
+
+	"github.com/pusher/pusher-platform-go/metrics"
+	"github.com/pusher/pusher-platform-go/tracing"
 )
 
 // BodyNotJSONError indicates a response to an elements request has a body that
@@ -14,44 +17,102 @@ import (
 type BodyNotJSONError struct {
 	JSONDecodeError error
 	StatusCode      int
-	BodyBytes       []byte
+	BodyBytes       []byte // The body, up to the maximum error body size of the client
+	ContentType     string // Content type of the response
+	Truncated       bool   // Whether the body exceeded the maximum error body size
+	RequestID       string // Request ID the request was sent with
+	IdempotencyKey  string // Idempotency key the request was sent with, if any
 }
 
-// Implements the Error interface.
+// Implements the Error interface. The body is shown as a truncated text preview.
 func (e BodyNotJSONError) Error() string {
 	return fmt.Sprintf(
-		"Body is not valid JSON. Status: %v Body: %v Error: %s",
+		"Body is not valid JSON. Status: %v Body: %s Error: %s",
 		e.StatusCode,
-		e.BodyBytes,
+		bodyPreview(e.BodyBytes, e.ContentType, e.Truncated),
 		e.JSONDecodeError,
 	)
 }
 
+// Is reports whether the status of the response belongs to the given sentinel error class.
+func (e BodyNotJSONError) Is(target error) bool {
+	return statusIs(e.StatusCode, target)
+}
+
+// Unwrap returns the JSON decoding error.
+func (e BodyNotJSONError) Unwrap() error {
+	return e.JSONDecodeError
+}
+
 // ErrorResponse represents information that is returned in case of an error.
 type ErrorResponse struct {
-	Status  int         `json:"status"`
-	Headers http.Header `json:"headers"`
-	Info    interface{} `json:"info"`
+	Status         int         `json:"status"`
+	Headers        http.Header `json:"headers"`
+	Info           interface{} `json:"info"`
+	RequestID      string      `json:"request_id,omitempty"`      // Request ID the request was sent with
+	IdempotencyKey string      `json:"idempotency_key,omitempty"` // Idempotency key the request was sent with, if any
 }
 
 func (e *ErrorResponse) Error() string {
 	return fmt.Sprintf("Error response: %d, %v", e.Status, e.Info)
 }
 
+// Unwrap returns the typed PlatformError that describes the error response.
+func (e *ErrorResponse) Unwrap() error {
+	return newPlatformError(e.Status, e.Headers, e.Info, e.RequestID)
+}
+
 // RequestOptions is used to configure HTTP requests.
 type RequestOptions struct {
-	Method      string
-	Path        string
-	Jwt         *string
-	Headers     *http.Header
-	Body        io.Reader
-	QueryParams *url.Values
+	Method        string
+	Path          string
+	Jwt           *string
+	Headers       *http.Header
+	Body          io.Reader
+	QueryParams   *url.Values
+	ContentLength int64 // Optional length of a Body whose length can not be determined
+
+	// IdempotencyKey is sent as the Idempotency-Key header and kept across retries.
+	// It is generated for non-idempotent methods when retries are enabled.
+	IdempotencyKey string
+
+	// Timeout optionally limits the whole call, including retries and reading the
+	// response body, replacing the timeout of the client. The earlier of it and the
+	// deadline of the context applies. It is ignored by subscriptions.
+	Timeout time.Duration
+
+	RetryPolicy        *RetryPolicy    // Optional retry policy replacing that of the client, ignored by subscriptions
+	DontFollowRedirect bool            // Return redirect responses instead of following them
+	RedirectPolicy     *RedirectPolicy // Optional redirect policy replacing that of the client
 }
 
 // Options includes configuration options for a new base client.
 type Options struct {
 	Host               string
+	Endpoint           *Endpoint        // Optional endpoint that requests are sent to, Host is ignored if set
+	Failover           *FailoverOptions // Optional endpoints that requests fail over between, Host and Endpoint are ignored if set
 	TLSConfig          *tls.Config
-	Timeout            time.Duration
+	TLS                *TLSOptions       // Optional client certificates, root CAs and pinning, applied on top of TLSConfig
+	Transport          http.RoundTripper // Optional transport that requests are sent with, TLSConfig, TLS and TransportOptions are ignored if set
+	TransportOptions   TransportOptions  // Configures the transport built when no Transport is provided
+	Timeout            time.Duration     // Optional timeout of requests, excluding subscriptions
 	DontFollowRedirect bool
+	RedirectPolicy     *RedirectPolicy // Optional policy for following redirects
+	Logger             Logger          // Optional logger for requests
+	Logging            LoggingOptions  // Configures what is logged when a Logger is provided
+	Metrics            metrics.Metrics // Optional metrics that requests are reported to
+	MetricsLabels      metrics.Labels  // Default labels of the reported metrics
+	Tracer             tracing.Tracer  // Optional tracer that starts a span for each request
+	RetryPolicy        *RetryPolicy    // Optional policy for retrying failed requests
+	RateLimiter        *RateLimiter    // Optional limiter pacing requests to each host
+	Cache              Cache           // Optional cache of GET responses, which are revalidated with ETag and Last-Modified
+	CoalesceRequests   bool            // Share one request between concurrent GET requests with the same path, query, headers and settings
+	MaxErrorBodySize   int64           // Maximum bytes of error response bodies that are read, defaults to 64KiB
+
+	// Compression enables compressed responses and request bodies when set.
+	Compression *CompressionOptions
+
+	// MaxCacheableBodySize is the maximum bytes of response bodies that are cached, defaults to 1MiB.
+	// Larger responses are returned without being cached.
+	MaxCacheableBodySize int64
 }
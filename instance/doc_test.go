@@ -125,3 +125,33 @@ func ExampleParseKey() {
 	fmt.Printf("Key: %s", components.Key)
 	fmt.Printf("Secret: %s", components.Secret)
 }
+
+func ExampleDoJSON() {
+	serviceInstance, err := instance.New(instance.Options{
+		Locator:        "version:cluster:instance-id",
+		Key:            "key:secret",
+		ServiceName:    "service-name",
+		ServiceVersion: "service-version",
+	})
+	if err != nil {
+		// Do something with error
+	}
+
+	type user struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+
+	created, err := instance.DoJSON[user, user](
+		context.Background(),
+		serviceInstance,
+		"POST",
+		"/users",
+		user{Name: "alice"},
+	)
+	if err != nil {
+		// Do something with error
+	}
+
+	fmt.Println(created.ID)
+}
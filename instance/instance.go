@@ -11,9 +11,12 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"time"
 
 	"github.com/pusher/pusher-platform-go/auth"
 	"github.com/pusher/pusher-platform-go/client"
+	"github.com/pusher/pusher-platform-go/metrics"
+	"github.com/pusher/pusher-platform-go/tracing"
 )
 
 var (
@@ -30,6 +33,12 @@ func init() {
 // It also allows access to the authenticator interface.
 type Instance interface {
 	Request(ctx context.Context, options client.RequestOptions) (*http.Response, error)
+	Subscribe(ctx context.Context, options client.RequestOptions) (client.Subscription, error)
+	SubscribeResumable(
+		ctx context.Context,
+		options client.RequestOptions,
+		resumeOptions client.ResumeOptions,
+	) (client.ResumableSubscription, error)
 	Authenticate(payload auth.Payload, options auth.Options) (*auth.Response, error)
 	GenerateAccessToken(options auth.Options) (auth.TokenWithExpiry, error)
 }
@@ -41,6 +50,21 @@ type Options struct {
 	ServiceName    string        // Service name to connect to
 	ServiceVersion string        // Version of service to connect to
 	Client         client.Client // Optional Client, if not provided will be constructed
+
+	// Endpoint optionally overrides the scheme, host, port and path prefix of the
+	// platform for the constructed Client. A {cluster} placeholder in its host is
+	// replaced by the cluster of the locator, and the host defaults to that of the cluster.
+	Endpoint *client.Endpoint
+
+	Logger           client.Logger           // Optional logger used by the constructed Client
+	Logging          client.LoggingOptions   // Logging options used by the constructed Client
+	Metrics          metrics.Metrics         // Optional metrics that requests and tokens are reported to
+	Tracer           tracing.Tracer          // Optional tracer used by the constructed Client
+	Timeout          time.Duration           // Optional timeout of requests made by the constructed Client
+	TransportOptions client.TransportOptions // Transport options used by the constructed Client
+	TLS              *client.TLSOptions      // Optional client certificates, root CAs and pinning used by the constructed Client
+	Cache            client.Cache            // Optional cache of GET responses used by the constructed Client
+	CoalesceRequests bool                    // Share one request between concurrent identical GET requests of the constructed Client
 }
 
 type instance struct {
@@ -55,6 +79,7 @@ type instance struct {
 
 	authenticator auth.Authenticator
 	client        client.Client
+	labels        metrics.Labels
 }
 
 // New creates a new instance satisfying the Instance interface.
@@ -80,10 +105,40 @@ func New(options Options) (Instance, error) {
 		return nil, errors.New("No service version provided")
 	}
 
+	labels := metrics.Labels{
+		Service: options.ServiceName,
+		Version: options.ServiceVersion,
+		Cluster: locatorComponents.Cluster,
+	}
+
+	var endpoint *client.Endpoint
+	if options.Endpoint != nil {
+		resolved := options.Endpoint.WithCluster(locatorComponents.Cluster)
+		if resolved.Host == "" {
+			resolved.Host = locatorComponents.Host()
+		}
+
+		if err := resolved.Validate(); err != nil {
+			return nil, err
+		}
+		endpoint = &resolved
+	}
+
 	underlyingClient := options.Client
 	if options.Client == nil {
 		underlyingClient = client.New(client.Options{
-			Host: locatorComponents.Host(),
+			Host:             locatorComponents.Host(),
+			Endpoint:         endpoint,
+			Logger:           options.Logger,
+			Logging:          options.Logging,
+			Metrics:          options.Metrics,
+			MetricsLabels:    labels,
+			Tracer:           options.Tracer,
+			Timeout:          options.Timeout,
+			TransportOptions: options.TransportOptions,
+			TLS:              options.TLS,
+			Cache:            options.Cache,
+			CoalesceRequests: options.CoalesceRequests,
 		})
 	}
 
@@ -95,12 +150,15 @@ func New(options Options) (Instance, error) {
 		platformVersion: locatorComponents.PlatformVersion,
 		keyID:           keyComponents.Key,
 		keySecret:       keyComponents.Secret,
-		authenticator: auth.New(
+		authenticator: auth.NewWithMetrics(
 			locatorComponents.InstanceID,
 			keyComponents.Key,
 			keyComponents.Secret,
+			options.Metrics,
+			labels,
 		),
 		client: underlyingClient,
+		labels: labels,
 	}, nil
 }
 
@@ -109,14 +167,34 @@ func (i *instance) Request(
 	ctx context.Context,
 	options client.RequestOptions,
 ) (*http.Response, error) {
-	return i.client.Request(ctx, client.RequestOptions{
-		Method:      options.Method,
-		Path:        i.scopePath(options.Path),
-		Jwt:         options.Jwt,
-		Headers:     options.Headers,
-		Body:        options.Body,
-		QueryParams: options.QueryParams,
-	})
+	ctx = i.metricsContext(ctx)
+	options.Path = i.scopePath(options.Path)
+
+	return i.client.Request(ctx, options)
+}
+
+// Subscribe allows opening subscriptions to services.
+func (i *instance) Subscribe(
+	ctx context.Context,
+	options client.RequestOptions,
+) (client.Subscription, error) {
+	ctx = i.metricsContext(ctx)
+	options.Path = i.scopePath(options.Path)
+
+	return i.client.Subscribe(ctx, options)
+}
+
+// SubscribeResumable allows opening subscriptions to services
+// that are resumed when the connection is lost.
+func (i *instance) SubscribeResumable(
+	ctx context.Context,
+	options client.RequestOptions,
+	resumeOptions client.ResumeOptions,
+) (client.ResumableSubscription, error) {
+	ctx = i.metricsContext(ctx)
+	options.Path = i.scopePath(options.Path)
+
+	return i.client.SubscribeResumable(ctx, options, resumeOptions)
 }
 
 // Authenticate exposes the Authenticator interface to allow
@@ -130,6 +208,11 @@ func (i *instance) GenerateAccessToken(options auth.Options) (auth.TokenWithExpi
 	return i.authenticator.GenerateAccessToken(options)
 }
 
+// metricsContext adds the labels of the instance to the context.
+func (i *instance) metricsContext(ctx context.Context) context.Context {
+	return metrics.ContextWithLabels(ctx, metrics.LabelsFromContext(ctx, i.labels))
+}
+
 func (i *instance) scopePath(path string) string {
 	return trailingSlashRegexp.ReplaceAllString(
 		slashFoldingRegexp.ReplaceAllString(
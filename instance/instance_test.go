@@ -6,9 +6,12 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/pusher/pusher-platform-go/client"
+	"github.com/pusher/pusher-platform-go/metrics"
 )
 
 func TestInstanceConstruction(t *testing.T) {
@@ -176,3 +179,242 @@ func TestInstanceRequestSuccessWithoutJwt(t *testing.T) {
 		t.Fatalf("Expected a 200 status code, but got %v", response.StatusCode)
 	}
 }
+
+func TestInstanceSubscribe(t *testing.T) {
+	instanceLocator := "v1:local:instance-id"
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services/test_service/v1/instance-id/test", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[1, "event-id", {}, {"foo": "bar"}]` + "\n"))
+		w.Write([]byte(`[255, 200, {}, null]` + "\n"))
+	})
+
+	server := httptest.NewTLSServer(mux)
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %+v", err)
+	}
+
+	underlyingClient := client.New(client.Options{
+		Host: uri.Host,
+		TLSConfig: &tls.Config{
+			InsecureSkipVerify: true,
+		},
+	})
+
+	instance, err := New(Options{
+		Locator:        instanceLocator,
+		Key:            "key:secret",
+		ServiceName:    "test_service",
+		ServiceVersion: "v1",
+		Client:         underlyingClient,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error when constructing an instance, but got %+v", err)
+	}
+
+	subscription, err := instance.Subscribe(context.Background(), client.RequestOptions{
+		Path: "/test",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error when subscribing, but got %+v", err)
+	}
+	defer subscription.Close()
+
+	event := <-subscription.Events()
+	if event.ID != "event-id" {
+		t.Fatalf("Expected event ID to be `event-id`, but got `%s`", event.ID)
+	}
+}
+
+func TestInstanceMetricsContext(t *testing.T) {
+	i := &instance{
+		instanceID:     "instance-id",
+		serviceName:    "test_service",
+		serviceVersion: "v1",
+		labels: metrics.Labels{
+			Service: "test_service",
+			Version: "v1",
+			Cluster: "local",
+		},
+	}
+
+	ctx := i.metricsContext(context.Background())
+	if endpoint, ok := metrics.EndpointFromContext(ctx); ok {
+		t.Fatalf("Expected no endpoint to be set, but got `%s`", endpoint)
+	}
+
+	labels := metrics.LabelsFromContext(ctx, metrics.Labels{})
+	if labels != i.labels {
+		t.Fatalf("Expected instance labels in context, but got %+v", labels)
+	}
+
+	ctx = metrics.ContextWithEndpoint(context.Background(), "/users/:id")
+	ctx = i.metricsContext(ctx)
+	if endpoint, _ := metrics.EndpointFromContext(ctx); endpoint != "/users/:id" {
+		t.Fatalf("Expected endpoint set by the caller to be kept, but got `%s`", endpoint)
+	}
+}
+
+func TestInstanceEndpoint(t *testing.T) {
+	var receivedPath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		receivedPath = r.URL.Path
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %+v", err)
+	}
+
+	instance, err := New(Options{
+		Locator:        "v1:local:instance-id",
+		Key:            "key:secret",
+		ServiceName:    "test_service",
+		ServiceVersion: "v1",
+		Endpoint: &client.Endpoint{
+			Scheme:     "http",
+			Host:       uri.Host,
+			PathPrefix: "/emulator",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error when constructing an instance, but got %+v", err)
+	}
+
+	response, err := instance.Request(context.Background(), client.RequestOptions{
+		Method: http.MethodGet,
+		Path:   "/users",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %+v", err)
+	}
+	response.Body.Close()
+
+	if receivedPath != "/emulator/services/test_service/v1/instance-id/users" {
+		t.Fatalf("Expected the request to be sent to the endpoint, but got %s", receivedPath)
+	}
+
+	t.Run("cluster placeholder", func(t *testing.T) {
+		instance, err := New(Options{
+			Locator:        "v1:us1:instance-id",
+			Key:            "key:secret",
+			ServiceName:    "test_service",
+			ServiceVersion: "v1",
+			Endpoint:       &client.Endpoint{Host: "{cluster}.staging.example.com"},
+		})
+		if err != nil {
+			t.Fatalf("Expected no error when constructing an instance, but got %+v", err)
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		_, err = instance.Request(ctx, client.RequestOptions{Method: http.MethodGet, Path: "/users"})
+		if err == nil || !strings.Contains(err.Error(), "https://us1.staging.example.com/services/") {
+			t.Fatalf("Expected the request to be sent to the cluster host, but got %+v", err)
+		}
+	})
+
+	t.Run("invalid endpoint", func(t *testing.T) {
+		_, err := New(Options{
+			Locator:        "v1:local:instance-id",
+			Key:            "key:secret",
+			ServiceName:    "test_service",
+			ServiceVersion: "v1",
+			Endpoint:       &client.Endpoint{Scheme: "ftp"},
+		})
+		if err == nil || err.Error() != "Unsupported endpoint scheme: ftp" {
+			t.Fatalf("Expected an invalid endpoint error, but got %+v", err)
+		}
+	})
+}
+
+// recordingClient records the options of the requests it is given.
+type recordingClient struct {
+	client.Client
+	options client.RequestOptions
+}
+
+func (c *recordingClient) Request(ctx context.Context, options client.RequestOptions) (*http.Response, error) {
+	c.options = options
+	return &http.Response{StatusCode: http.StatusOK, Body: http.NoBody}, nil
+}
+
+func TestInstanceRequestPassesOptionsThrough(t *testing.T) {
+	recorder := &recordingClient{}
+	instance, err := New(Options{
+		Locator:        "v1:local:instance-id",
+		Key:            "key:secret",
+		ServiceName:    "test_service",
+		ServiceVersion: "v1",
+		Client:         recorder,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error when constructing an instance, but got %+v", err)
+	}
+
+	retryPolicy := &client.RetryPolicy{MaxRetries: 3}
+	redirectPolicy := &client.RedirectPolicy{MaxRedirects: 2}
+	_, err = instance.Request(context.Background(), client.RequestOptions{
+		Method:             http.MethodGet,
+		Path:               "/users",
+		Timeout:            time.Second,
+		RetryPolicy:        retryPolicy,
+		DontFollowRedirect: true,
+		RedirectPolicy:     redirectPolicy,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error when performing a request, but got %+v", err)
+	}
+
+	options := recorder.options
+	if options.Path != "/services/test_service/v1/instance-id/users" {
+		t.Fatalf("Expected the path to be scoped, but got %s", options.Path)
+	}
+
+	if options.Timeout != time.Second ||
+		options.RetryPolicy != retryPolicy ||
+		!options.DontFollowRedirect ||
+		options.RedirectPolicy != redirectPolicy {
+		t.Fatalf("Expected the options to be passed through, but got %+v", options)
+	}
+}
+
+// newTestInstance starts a TLS test server for the handler, and returns an instance
+// whose client has the given options and trusts it, along with a function that
+// closes the server.
+func newTestInstance(t *testing.T, handler http.Handler, options client.Options) (Instance, func()) {
+	server := httptest.NewTLSServer(handler)
+
+	uri, err := url.Parse(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to parse server URL: %+v", err)
+	}
+
+	options.Host = uri.Host
+	if options.Endpoint != nil {
+		options.Endpoint.Host = uri.Host
+	}
+	options.TLSConfig = &tls.Config{
+		InsecureSkipVerify: true,
+	}
+
+	instance, err := New(Options{
+		Locator:        "v1:local:instance-id",
+		Key:            "key:secret",
+		ServiceName:    "test_service",
+		ServiceVersion: "v1",
+		Client:         client.New(options),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error when constructing an instance, but got %+v", err)
+	}
+
+	return instance, server.Close
+}
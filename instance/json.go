@@ -0,0 +1,131 @@
+package instance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"github.com/pusher/pusher-platform-go/client"
+)
+
+const (
+	contentTypeHeader = "Content-Type"
+	jsonContentType   = "application/json"
+)
+
+// EncodeError indicates the body of a request could not be encoded as JSON.
+type EncodeError struct {
+	Err error
+}
+
+// Implements the Error interface.
+func (e *EncodeError) Error() string {
+	return fmt.Sprintf("Failed to encode request body: %s", e.Err)
+}
+
+// Unwrap returns the underlying encoding error.
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeError indicates the body of a successful response could not be decoded.
+type DecodeError struct {
+	StatusCode int
+	Headers    http.Header
+	Err        error
+}
+
+// Implements the Error interface.
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("Failed to decode response body. Status: %d Error: %s", e.StatusCode, e.Err)
+}
+
+// Unwrap returns the underlying decoding error.
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DoJSON makes a request to a service with a JSON encoded body and
+// decodes the JSON body of a successful response into Resp.
+//
+// A nil request is sent without a body. Empty response bodies, such as
+// those of 204 responses, leave Resp as its zero value.
+func DoJSON[Req, Resp any](
+	ctx context.Context,
+	inst Instance,
+	method string,
+	path string,
+	req Req,
+) (Resp, error) {
+	return DoJSONWithOptions[Req, Resp](ctx, inst, client.RequestOptions{
+		Method: method,
+		Path:   path,
+	}, req)
+}
+
+// DoJSONWithOptions is like DoJSON but allows setting the JWT, headers and
+// query parameters of the request through the options.
+//
+// The body of the options is replaced by the encoded request.
+func DoJSONWithOptions[Req, Resp any](
+	ctx context.Context,
+	inst Instance,
+	options client.RequestOptions,
+	req Req,
+) (Resp, error) {
+	var result Resp
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return result, &EncodeError{err}
+	}
+
+	headers := http.Header{}
+	if options.Headers != nil {
+		for key, values := range *options.Headers {
+			headers[key] = append([]string(nil), values...)
+		}
+	}
+	headers.Set("Accept", jsonContentType)
+
+	options.Body = nil
+	if !bytes.Equal(body, []byte("null")) {
+		options.Body = bytes.NewReader(body)
+		headers.Set(contentTypeHeader, jsonContentType)
+	}
+	options.Headers = &headers
+
+	response, err := inst.Request(ctx, options)
+	if err != nil {
+		return result, err
+	}
+	defer closeBody(response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return result, &client.ErrorResponse{
+			Status:  response.StatusCode,
+			Headers: response.Header,
+		}
+	}
+
+	err = json.NewDecoder(response.Body).Decode(&result)
+	if err != nil && err != io.EOF {
+		return result, &DecodeError{
+			StatusCode: response.StatusCode,
+			Headers:    response.Header,
+			Err:        err,
+		}
+	}
+
+	return result, nil
+}
+
+// closeBody drains and closes a response body so the connection can be reused.
+func closeBody(body io.ReadCloser) {
+	_, _ = io.Copy(ioutil.Discard, body)
+	_ = body.Close()
+}
@@ -0,0 +1,134 @@
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/pusher/pusher-platform-go/client"
+)
+
+type testUser struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+}
+
+func TestDoJSON(t *testing.T) {
+	var receivedContentType string
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services/test_service/v1/instance-id/users", func(w http.ResponseWriter, r *http.Request) {
+		receivedContentType = r.Header.Get(contentTypeHeader)
+
+		var user testUser
+		if err := json.NewDecoder(r.Body).Decode(&user); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_body"}`))
+			return
+		}
+
+		user.ID = "user-id"
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(user)
+	})
+	mux.HandleFunc("/services/test_service/v1/instance-id/empty", func(w http.ResponseWriter, r *http.Request) {
+		receivedContentType = r.Header.Get(contentTypeHeader)
+		w.WriteHeader(http.StatusNoContent)
+	})
+	mux.HandleFunc("/services/test_service/v1/instance-id/not_json", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("not json"))
+	})
+
+	instance, closeServer := newTestInstance(t, mux, client.Options{})
+	defer closeServer()
+
+	t.Run("Request and response bodies are encoded", func(t *testing.T) {
+		user, err := DoJSON[testUser, testUser](
+			context.Background(),
+			instance,
+			http.MethodPost,
+			"/users",
+			testUser{Name: "alice"},
+		)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+
+		if user.ID != "user-id" || user.Name != "alice" {
+			t.Fatalf("Unexpected response %+v", user)
+		}
+
+		if receivedContentType != jsonContentType {
+			t.Fatalf("Expected content type `%s`, but got `%s`", jsonContentType, receivedContentType)
+		}
+	})
+
+	t.Run("Nil requests and empty responses are allowed", func(t *testing.T) {
+		user, err := DoJSON[*testUser, *testUser](
+			context.Background(),
+			instance,
+			http.MethodDelete,
+			"/empty",
+			nil,
+		)
+		if err != nil {
+			t.Fatalf("Expected no error, but got %+v", err)
+		}
+
+		if user != nil {
+			t.Fatalf("Expected no response, but got %+v", user)
+		}
+
+		if receivedContentType != "" {
+			t.Fatalf("Expected no content type, but got `%s`", receivedContentType)
+		}
+	})
+
+	t.Run("Error responses are returned", func(t *testing.T) {
+		_, err := DoJSON[string, testUser](
+			context.Background(),
+			instance,
+			http.MethodPost,
+			"/users",
+			"not a user",
+		)
+
+		var errorResponse *client.ErrorResponse
+		if !errors.As(err, &errorResponse) || errorResponse.Status != http.StatusBadRequest {
+			t.Fatalf("Expected a 400 ErrorResponse, but got %v", err)
+		}
+	})
+
+	t.Run("Invalid response bodies are reported", func(t *testing.T) {
+		_, err := DoJSON[*testUser, testUser](
+			context.Background(),
+			instance,
+			http.MethodGet,
+			"/not_json",
+			nil,
+		)
+
+		var decodeError *DecodeError
+		if !errors.As(err, &decodeError) || decodeError.StatusCode != http.StatusOK {
+			t.Fatalf("Expected a DecodeError, but got %v", err)
+		}
+	})
+
+	t.Run("Unencodable requests are reported", func(t *testing.T) {
+		_, err := DoJSON[chan int, testUser](
+			context.Background(),
+			instance,
+			http.MethodPost,
+			"/users",
+			make(chan int),
+		)
+
+		var encodeError *EncodeError
+		if !errors.As(err, &encodeError) {
+			t.Fatalf("Expected an EncodeError, but got %v", err)
+		}
+	})
+}
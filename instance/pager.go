@@ -0,0 +1,398 @@
+package instance
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/pusher/pusher-platform-go/client"
+)
+
+const (
+	linkHeader           = "Link"
+	defaultPageSizeParam = "limit"
+	defaultCursorParam   = "cursor"
+	defaultItemsField    = "items"
+	defaultCursorField   = "next_cursor"
+)
+
+// PageOptions configures how a Pager requests and reads pages.
+//
+// Pages are either a JSON array of items, or a JSON object holding the items
+// and the cursor of the next page. The next page is requested with the path
+// and query of the `next` link of the Link header if there is one, and
+// otherwise with the cursor, until there is neither.
+type PageOptions struct {
+	PageSize int  // Optional number of items requested per page
+	Limit    int  // Optional maximum number of items returned in total
+	Prefetch bool // Whether the next page is requested while the current one is read
+
+	PageSizeParam string // Query parameter of the page size, defaults to `limit`
+	CursorParam   string // Query parameter of the cursor, defaults to `cursor`
+	ItemsField    string // Field holding the items of object pages, defaults to `items`
+	CursorField   string // Field holding the next cursor of object pages, defaults to `next_cursor`
+}
+
+// Pager iterates over the items of a paginated list endpoint.
+//
+//	pager := instance.NewPager[user](serviceInstance, client.RequestOptions{Path: "/users"}, instance.PageOptions{})
+//	for pager.Next(ctx) {
+//		user := pager.Item()
+//	}
+//	if err := pager.Err(); err != nil {
+//		...
+//	}
+//
+// A Pager is not safe for concurrent use.
+type Pager[T any] struct {
+	inst        Instance
+	options     client.RequestOptions
+	pageOptions PageOptions
+
+	items    []T
+	item     T
+	returned int
+	next     *pageRequest // Next page, nil once there are no more pages
+	pending  chan pageResult[T]
+	err      error
+}
+
+type pageResult[T any] struct {
+	items []T
+	next  *pageRequest
+	err   error
+}
+
+// pageRequest is the path, relative to the service, and the query of a page.
+type pageRequest struct {
+	path  string
+	query url.Values
+}
+
+// NewPager returns a Pager over the items of the endpoint described by the options.
+// No request is made until Next is called.
+func NewPager[T any](inst Instance, options client.RequestOptions, pageOptions PageOptions) *Pager[T] {
+	if options.Method == "" {
+		options.Method = http.MethodGet
+	}
+
+	if pageOptions.PageSizeParam == "" {
+		pageOptions.PageSizeParam = defaultPageSizeParam
+	}
+	if pageOptions.CursorParam == "" {
+		pageOptions.CursorParam = defaultCursorParam
+	}
+	if pageOptions.ItemsField == "" {
+		pageOptions.ItemsField = defaultItemsField
+	}
+	if pageOptions.CursorField == "" {
+		pageOptions.CursorField = defaultCursorField
+	}
+
+	next := &pageRequest{path: options.Path, query: url.Values{}}
+	if options.QueryParams != nil {
+		for key, values := range *options.QueryParams {
+			next.query[key] = append([]string(nil), values...)
+		}
+	}
+
+	return &Pager[T]{
+		inst:        inst,
+		options:     options,
+		pageOptions: pageOptions,
+		next:        next,
+	}
+}
+
+// Next advances to the next item, requesting the next page when needed.
+// It returns false once there are no more items, the limit is reached,
+// a request fails or the context is done, after which Err should be checked.
+func (p *Pager[T]) Next(ctx context.Context) bool {
+	if p.err != nil || p.limitReached() {
+		return false
+	}
+
+	if err := ctx.Err(); err != nil {
+		p.err = err
+		return false
+	}
+
+	for len(p.items) == 0 {
+		if p.next == nil && p.pending == nil {
+			return false
+		}
+
+		result := p.nextPage(ctx)
+		if result.err != nil {
+			p.err = result.err
+			return false
+		}
+
+		p.items = result.items
+		p.next = result.next
+		if p.pageOptions.Prefetch {
+			p.prefetch(ctx)
+		}
+	}
+
+	p.item = p.items[0]
+	p.items = p.items[1:]
+	p.returned++
+
+	return true
+}
+
+// Item returns the current item.
+func (p *Pager[T]) Item() T {
+	return p.item
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (p *Pager[T]) Err() error {
+	return p.err
+}
+
+// limitReached reports whether the limit of items has been returned.
+func (p *Pager[T]) limitReached() bool {
+	return p.pageOptions.Limit > 0 && p.returned >= p.pageOptions.Limit
+}
+
+// nextPage returns the prefetched page if there is one, and requests the next page otherwise.
+func (p *Pager[T]) nextPage(ctx context.Context) pageResult[T] {
+	pending := p.pending
+	if pending == nil {
+		return p.fetch(ctx, p.next, p.returned)
+	}
+	p.pending = nil
+
+	select {
+	case result := <-pending:
+		return result
+	case <-ctx.Done():
+		return pageResult[T]{err: ctx.Err()}
+	}
+}
+
+// prefetch requests the next page in the background, unless the
+// current page already holds the remaining items.
+func (p *Pager[T]) prefetch(ctx context.Context) {
+	returned := p.returned + len(p.items)
+	if p.next == nil || (p.pageOptions.Limit > 0 && returned >= p.pageOptions.Limit) {
+		return
+	}
+
+	// The channel is buffered so the request completes even if the result is never read.
+	pending := make(chan pageResult[T], 1)
+	next := p.next
+	go func() {
+		pending <- p.fetch(ctx, next, returned)
+	}()
+
+	p.pending = pending
+	p.next = nil
+}
+
+// fetch requests the given page.
+func (p *Pager[T]) fetch(ctx context.Context, page *pageRequest, returned int) pageResult[T] {
+	params := url.Values{}
+	for key, values := range page.query {
+		params[key] = append([]string(nil), values...)
+	}
+
+	if pageSize := p.pageSize(returned); pageSize > 0 {
+		params.Set(p.pageOptions.PageSizeParam, strconv.Itoa(pageSize))
+	}
+
+	options := p.options
+	options.Path = page.path
+	options.QueryParams = &params
+
+	response, err := p.inst.Request(ctx, options)
+	if err != nil {
+		return pageResult[T]{err: err}
+	}
+	defer closeBody(response.Body)
+
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return pageResult[T]{err: &client.ErrorResponse{
+			Status:    response.StatusCode,
+			Headers:   response.Header,
+			RequestID: client.ResponseRequestID(response),
+		}}
+	}
+
+	var body json.RawMessage
+	if err := json.NewDecoder(response.Body).Decode(&body); err != nil {
+		return pageResult[T]{err: &DecodeError{
+			StatusCode: response.StatusCode,
+			Headers:    response.Header,
+			Err:        err,
+		}}
+	}
+
+	items, cursor, err := p.decodePage(body)
+	if err != nil {
+		return pageResult[T]{err: &DecodeError{
+			StatusCode: response.StatusCode,
+			Headers:    response.Header,
+			Err:        err,
+		}}
+	}
+
+	var next *pageRequest
+	if link, ok := nextLink(response.Header); ok {
+		next, err = p.linkedPage(page, response.Request, link)
+		if err != nil {
+			return pageResult[T]{err: err}
+		}
+	} else if cursor != "" {
+		next = &pageRequest{path: page.path, query: params}
+		next.query.Del(p.pageOptions.PageSizeParam)
+		next.query.Set(p.pageOptions.CursorParam, cursor)
+	}
+
+	if next != nil && p.samePage(page, next) {
+		return pageResult[T]{err: fmt.Errorf("Next page of %s is the same page", page.path)}
+	}
+
+	return pageResult[T]{items: items, next: next}
+}
+
+// linkedPage returns the page of a `next` link, which is resolved against the URL
+// of the request of the current page. Links to other paths of the service are
+// requested through the instance, other paths are taken to be relative to the
+// service, and links to other hosts are not followed.
+func (p *Pager[T]) linkedPage(page *pageRequest, request *http.Request, link *url.URL) (*pageRequest, error) {
+	current := &url.URL{}
+	if request != nil {
+		current = request.URL
+	}
+
+	target := current.ResolveReference(link)
+	if target.Host != current.Host {
+		return nil, fmt.Errorf("Next page link %s is on another host", link)
+	}
+
+	next := &pageRequest{path: page.path, query: target.Query()}
+	if target.Path == current.Path {
+		return next, nil
+	}
+
+	// The path of the current request ends with the path of the page, and
+	// starts with the path prefix of the client and the scope of the service.
+	var base string
+	if request != nil {
+		suffix := slashFoldingRegexp.ReplaceAllString("/"+page.path, "/")
+		if !strings.HasSuffix(current.Path, suffix) {
+			return nil, fmt.Errorf("Next page link %s can not be resolved against %s", link, current.Path)
+		}
+		base = strings.TrimSuffix(strings.TrimSuffix(current.Path, suffix), "/")
+	}
+
+	switch {
+	case target.Path == base:
+		next.path = "/"
+	case strings.HasPrefix(target.Path, base+"/"):
+		next.path = strings.TrimPrefix(target.Path, base)
+	default:
+		next.path = target.Path
+	}
+
+	return next, nil
+}
+
+// samePage reports whether two pages are requested with the same path and query,
+// ignoring the page size, in which case following the next page would never end.
+func (p *Pager[T]) samePage(page *pageRequest, next *pageRequest) bool {
+	query := func(page *pageRequest) string {
+		values := url.Values{}
+		for key, value := range page.query {
+			values[key] = value
+		}
+		values.Del(p.pageOptions.PageSizeParam)
+		return values.Encode()
+	}
+
+	return page.path == next.path && query(page) == query(next)
+}
+
+// pageSize returns the page size to request, which is zero if no page size is set.
+func (p *Pager[T]) pageSize(returned int) int {
+	pageSize := p.pageOptions.PageSize
+	if remaining := p.pageOptions.Limit - returned; p.pageOptions.Limit > 0 && remaining < pageSize {
+		return remaining
+	}
+
+	return pageSize
+}
+
+// decodePage decodes the items of a page and the cursor of the next page, if any.
+func (p *Pager[T]) decodePage(body json.RawMessage) ([]T, string, error) {
+	var items []T
+	if bytes.HasPrefix(bytes.TrimSpace(body), []byte("[")) {
+		err := json.Unmarshal(body, &items)
+		return items, "", err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return nil, "", err
+	}
+
+	raw, ok := fields[p.pageOptions.ItemsField]
+	if !ok {
+		return nil, "", fmt.Errorf("Page has no %s field", p.pageOptions.ItemsField)
+	}
+
+	if err := json.Unmarshal(raw, &items); err != nil {
+		return nil, "", err
+	}
+
+	var cursor *string
+	if raw, ok := fields[p.pageOptions.CursorField]; ok {
+		if err := json.Unmarshal(raw, &cursor); err != nil {
+			return nil, "", errors.New("Cursor must be a string")
+		}
+	}
+
+	if cursor == nil {
+		return items, "", nil
+	}
+
+	return items, *cursor, nil
+}
+
+// nextLink returns the URL of the `next` link of the Link header.
+func nextLink(headers http.Header) (*url.URL, bool) {
+	for _, header := range headers.Values(linkHeader) {
+		for _, link := range strings.Split(header, ",") {
+			parts := strings.Split(link, ";")
+			target := strings.TrimSpace(parts[0])
+			if !strings.HasPrefix(target, "<") || !strings.HasSuffix(target, ">") {
+				continue
+			}
+
+			for _, param := range parts[1:] {
+				key, value, _ := strings.Cut(strings.TrimSpace(param), "=")
+				if !strings.EqualFold(key, "rel") {
+					continue
+				}
+
+				for _, rel := range strings.Fields(strings.Trim(value, `"`)) {
+					if strings.EqualFold(rel, "next") {
+						uri, err := url.Parse(target[1 : len(target)-1])
+						return uri, err == nil
+					}
+				}
+			}
+		}
+	}
+
+	return nil, false
+}
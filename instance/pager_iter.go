@@ -0,0 +1,31 @@
+//go:build go1.23
+
+package instance
+
+import (
+	"context"
+	"iter"
+)
+
+// All returns an iterator over the remaining items and, if the iteration
+// stops because of an error, that error along with the zero value of T.
+//
+//	for user, err := range pager.All(ctx) {
+//		if err != nil {
+//			...
+//		}
+//	}
+func (p *Pager[T]) All(ctx context.Context) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for p.Next(ctx) {
+			if !yield(p.Item(), nil) {
+				return
+			}
+		}
+
+		if err := p.Err(); err != nil {
+			var zero T
+			yield(zero, err)
+		}
+	}
+}
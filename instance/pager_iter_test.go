@@ -0,0 +1,39 @@
+//go:build go1.23
+
+package instance
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/pusher/pusher-platform-go/client"
+)
+
+func TestPagerAll(t *testing.T) {
+	handler, _ := newPagedHandler(true)
+	mux := http.NewServeMux()
+	mux.Handle("/services/test_service/v1/instance-id/users", handler)
+
+	instance, closeServer := newTestInstance(t, mux, client.Options{})
+	defer closeServer()
+
+	pager := NewPager[testUser](instance, client.RequestOptions{Path: "/users"}, PageOptions{Prefetch: true})
+
+	ids := []string{}
+	for user, err := range pager.All(context.Background()) {
+		if err != nil {
+			t.Fatalf("Expected no error paging, but got %+v", err)
+		}
+
+		ids = append(ids, user.ID)
+		if len(ids) == 5 {
+			break
+		}
+	}
+
+	if fmt.Sprint(ids) != "[0 1 2 3 4]" {
+		t.Fatalf("Expected the first 5 users, but got %v", ids)
+	}
+}
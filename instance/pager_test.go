@@ -0,0 +1,296 @@
+package instance
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pusher/pusher-platform-go/client"
+)
+
+// newPagedHandler serves 10 users in pages, following cursors in the body or the Link header.
+func newPagedHandler(useLink bool) (http.Handler, *[]string) {
+	var (
+		mutex    sync.Mutex
+		requests []string
+	)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		requests = append(requests, r.URL.RawQuery)
+		mutex.Unlock()
+
+		start, _ := strconv.Atoi(r.URL.Query().Get("cursor"))
+		size, err := strconv.Atoi(r.URL.Query().Get("limit"))
+		if err != nil {
+			size = 3
+		}
+
+		end := start + size
+		if end > 10 {
+			end = 10
+		}
+
+		users := []testUser{}
+		for i := start; i < end; i++ {
+			users = append(users, testUser{ID: strconv.Itoa(i)})
+		}
+
+		if useLink {
+			if end < 10 {
+				w.Header().Set(linkHeader, fmt.Sprintf(`</users?cursor=%d&role=admin>; rel="next"`, end))
+			}
+			json.NewEncoder(w).Encode(users)
+			return
+		}
+
+		page := map[string]interface{}{"items": users, "next_cursor": nil}
+		if end < 10 {
+			page["next_cursor"] = strconv.Itoa(end)
+		}
+		json.NewEncoder(w).Encode(page)
+	})
+
+	return handler, &requests
+}
+
+func collectIDs(t *testing.T, pager *Pager[testUser]) []string {
+	ids := []string{}
+	for pager.Next(context.Background()) {
+		ids = append(ids, pager.Item().ID)
+	}
+
+	if err := pager.Err(); err != nil {
+		t.Fatalf("Expected no error paging, but got %+v", err)
+	}
+
+	return ids
+}
+
+func TestPager(t *testing.T) {
+	for _, useLink := range []bool{false, true} {
+		t.Run(fmt.Sprintf("link=%t", useLink), func(t *testing.T) {
+			handler, requests := newPagedHandler(useLink)
+			mux := http.NewServeMux()
+			mux.Handle("/services/test_service/v1/instance-id/users", handler)
+
+			instance, closeServer := newTestInstance(t, mux, client.Options{})
+			defer closeServer()
+
+			pager := NewPager[testUser](instance, client.RequestOptions{Path: "/users"}, PageOptions{PageSize: 4})
+			ids := collectIDs(t, pager)
+
+			if fmt.Sprint(ids) != "[0 1 2 3 4 5 6 7 8 9]" {
+				t.Fatalf("Expected all users, but got %v", ids)
+			}
+
+			if len(*requests) != 3 || (*requests)[0] != "limit=4" {
+				t.Fatalf("Expected 3 page requests with the page size, but got %v", *requests)
+			}
+		})
+	}
+}
+
+func TestPagerLimit(t *testing.T) {
+	for _, prefetch := range []bool{false, true} {
+		t.Run(fmt.Sprintf("prefetch=%t", prefetch), func(t *testing.T) {
+			handler, requests := newPagedHandler(false)
+			mux := http.NewServeMux()
+			mux.Handle("/services/test_service/v1/instance-id/users", handler)
+
+			instance, closeServer := newTestInstance(t, mux, client.Options{})
+			defer closeServer()
+
+			pager := NewPager[testUser](instance, client.RequestOptions{Path: "/users"}, PageOptions{
+				PageSize: 4,
+				Limit:    6,
+				Prefetch: prefetch,
+			})
+			ids := collectIDs(t, pager)
+
+			if fmt.Sprint(ids) != "[0 1 2 3 4 5]" {
+				t.Fatalf("Expected the first 6 users, but got %v", ids)
+			}
+
+			if len(*requests) != 2 || (*requests)[1] != "cursor=4&limit=2" {
+				t.Fatalf("Expected the last page to request the remaining users, but got %v", *requests)
+			}
+		})
+	}
+}
+
+func TestPagerLinkPaths(t *testing.T) {
+	for _, prefix := range []string{"", "/api"} {
+		t.Run(fmt.Sprintf("prefix=%q", prefix), func(t *testing.T) {
+			var (
+				mutex    sync.Mutex
+				requests []string
+			)
+
+			scope := prefix + "/services/test_service/v1/instance-id"
+			mux := http.NewServeMux()
+			mux.HandleFunc(scope+"/", func(w http.ResponseWriter, r *http.Request) {
+				mutex.Lock()
+				requests = append(requests, r.URL.RequestURI())
+				mutex.Unlock()
+
+				switch r.URL.Path {
+				case scope + "/users":
+					w.Header().Set(linkHeader, "<"+scope+`/users/page2>; rel="next"`)
+					w.Write([]byte(`[{"id": "1"}]`))
+				case scope + "/users/page2":
+					w.Header().Set(linkHeader, `<page3?role=admin>; rel="next"`)
+					w.Write([]byte(`[{"id": "2"}]`))
+				default:
+					w.Write([]byte(`[{"id": "3"}]`))
+				}
+			})
+
+			options := client.Options{}
+			if prefix != "" {
+				options.Endpoint = &client.Endpoint{PathPrefix: prefix}
+			}
+
+			instance, closeServer := newTestInstance(t, mux, options)
+			defer closeServer()
+
+			ids := collectIDs(t, NewPager[testUser](instance, client.RequestOptions{Path: "/users"}, PageOptions{}))
+			if fmt.Sprint(ids) != "[1 2 3]" {
+				t.Fatalf("Expected the users of every page, but got %v", ids)
+			}
+
+			expected := fmt.Sprintf("[%[1]s/users %[1]s/users/page2 %[1]s/users/page3?role=admin]", scope)
+			if fmt.Sprint(requests) != expected {
+				t.Fatalf("Expected the linked pages to be requested, but got %v", requests)
+			}
+		})
+	}
+
+}
+
+func TestPagerErrors(t *testing.T) {
+	t.Run("error response", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/services/test_service/v1/instance-id/users", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusForbidden)
+		})
+
+		instance, closeServer := newTestInstance(t, mux, client.Options{})
+		defer closeServer()
+
+		pager := NewPager[testUser](instance, client.RequestOptions{Path: "/users"}, PageOptions{})
+		if pager.Next(context.Background()) {
+			t.Fatalf("Expected no items")
+		}
+
+		if !errors.Is(pager.Err(), client.ErrForbidden) {
+			t.Fatalf("Expected a forbidden error, but got %+v", pager.Err())
+		}
+	})
+
+	t.Run("missing items", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/services/test_service/v1/instance-id/users", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(`{"users": []}`))
+		})
+
+		instance, closeServer := newTestInstance(t, mux, client.Options{})
+		defer closeServer()
+
+		pager := NewPager[testUser](instance, client.RequestOptions{Path: "/users"}, PageOptions{})
+		pager.Next(context.Background())
+
+		var decodeError *DecodeError
+		if !errors.As(pager.Err(), &decodeError) {
+			t.Fatalf("Expected a decode error, but got %+v", pager.Err())
+		}
+	})
+
+	t.Run("next page is the same page", func(t *testing.T) {
+		var requests int32
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/services/test_service/v1/instance-id/users", func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
+			w.Header().Set(linkHeader, `</services/test_service/v1/instance-id/users?limit=5>; rel="next"`)
+			w.Write([]byte(`[{"id": "1"}]`))
+		})
+
+		instance, closeServer := newTestInstance(t, mux, client.Options{})
+		defer closeServer()
+
+		pager := NewPager[testUser](instance, client.RequestOptions{Path: "/users"}, PageOptions{})
+		if pager.Next(context.Background()) || pager.Err() == nil {
+			t.Fatalf("Expected an error, but got %+v", pager.Err())
+		}
+
+		if count := atomic.LoadInt32(&requests); count != 1 {
+			t.Fatalf("Expected a single request, but got %d", count)
+		}
+	})
+
+	t.Run("next page on another host", func(t *testing.T) {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/services/test_service/v1/instance-id/users", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(linkHeader, `<https://example.com/users?cursor=2>; rel="next"`)
+			w.Write([]byte(`[{"id": "1"}]`))
+		})
+
+		instance, closeServer := newTestInstance(t, mux, client.Options{})
+		defer closeServer()
+
+		pager := NewPager[testUser](instance, client.RequestOptions{Path: "/users"}, PageOptions{})
+		if pager.Next(context.Background()) || pager.Err() == nil {
+			t.Fatalf("Expected an error, but got %+v", pager.Err())
+		}
+	})
+
+	t.Run("cancelled context", func(t *testing.T) {
+		handler, requests := newPagedHandler(false)
+		mux := http.NewServeMux()
+		mux.Handle("/services/test_service/v1/instance-id/users", handler)
+
+		instance, closeServer := newTestInstance(t, mux, client.Options{})
+		defer closeServer()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		pager := NewPager[testUser](instance, client.RequestOptions{Path: "/users"}, PageOptions{})
+		if !pager.Next(ctx) {
+			t.Fatalf("Expected an item, but got %+v", pager.Err())
+		}
+
+		cancel()
+
+		if pager.Next(ctx) {
+			t.Fatalf("Expected no items after the context was cancelled")
+		}
+
+		if pager.Err() != context.Canceled {
+			t.Fatalf("Expected the context error, but got %+v", pager.Err())
+		}
+
+		if len(*requests) != 1 {
+			t.Fatalf("Expected a single request, but got %v", *requests)
+		}
+	})
+}
+
+func TestNextLink(t *testing.T) {
+	headers := http.Header{}
+	headers.Add(linkHeader, `</users?cursor=1>; rel="prev", </users?cursor=3>; rel="next last"`)
+
+	link, ok := nextLink(headers)
+	if !ok || link.Query().Get("cursor") != "3" {
+		t.Fatalf("Expected the next link, but got %v", link)
+	}
+
+	if _, ok := nextLink(http.Header{}); ok {
+		t.Fatalf("Expected no next link")
+	}
+}
@@ -0,0 +1,41 @@
+package instance
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/pusher/pusher-platform-go/client"
+)
+
+// Upload streams a multipart/form-data body to a service.
+//
+// The method defaults to POST. The body of the options is replaced by the upload,
+// and the Content-Type header is set with the boundary of the multipart body.
+// The upload is cancelled when the context is done.
+func Upload(
+	ctx context.Context,
+	inst Instance,
+	options client.RequestOptions,
+	upload client.MultipartUpload,
+) (*http.Response, error) {
+	if options.Method == "" {
+		options.Method = http.MethodPost
+	}
+
+	body, contentType, length := upload.Body(ctx)
+	defer body.Close()
+
+	headers := http.Header{}
+	if options.Headers != nil {
+		for key, values := range *options.Headers {
+			headers[key] = append([]string(nil), values...)
+		}
+	}
+	headers.Set(contentTypeHeader, contentType)
+
+	options.Headers = &headers
+	options.Body = body
+	options.ContentLength = length
+
+	return inst.Request(ctx, options)
+}
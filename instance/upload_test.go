@@ -0,0 +1,97 @@
+package instance
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/pusher/pusher-platform-go/client"
+)
+
+func TestUpload(t *testing.T) {
+	var (
+		receivedMethod        string
+		receivedContentLength int64
+		receivedField         string
+		receivedFile          string
+		receivedHeader        string
+	)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/services/test_service/v1/instance-id/files", func(w http.ResponseWriter, r *http.Request) {
+		receivedMethod = r.Method
+		receivedContentLength = r.ContentLength
+		receivedHeader = r.Header.Get("X-Upload-Source")
+
+		reader, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		for {
+			part, err := reader.NextPart()
+			if err != nil {
+				break
+			}
+
+			content, _ := ioutil.ReadAll(part)
+			if part.FormName() == "file" {
+				receivedFile = string(content)
+			} else {
+				receivedField = string(content)
+			}
+		}
+
+		w.WriteHeader(http.StatusCreated)
+	})
+
+	instance, closeServer := newTestInstance(t, mux, client.Options{})
+	defer closeServer()
+
+	headers := http.Header{"X-Upload-Source": []string{"test"}}
+	response, err := Upload(context.Background(), instance, client.RequestOptions{
+		Path:    "/files",
+		Headers: &headers,
+	}, client.MultipartUpload{
+		Fields: map[string]string{"description": "report"},
+		Files: []client.MultipartFile{
+			{
+				FieldName: "file",
+				FileName:  "report.txt",
+				Content:   strings.NewReader("file contents"),
+				Size:      int64(len("file contents")),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Expected no error uploading, but got %+v", err)
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode != http.StatusCreated {
+		t.Fatalf("Expected status %d, but got %d", http.StatusCreated, response.StatusCode)
+	}
+
+	if receivedMethod != http.MethodPost {
+		t.Fatalf("Expected method %s, but got %s", http.MethodPost, receivedMethod)
+	}
+
+	if receivedContentLength <= 0 {
+		t.Fatalf("Expected the content length to be sent, but got %d", receivedContentLength)
+	}
+
+	if receivedField != "report" || receivedFile != "file contents" {
+		t.Fatalf("Expected the field and file to be sent, but got %s and %s", receivedField, receivedFile)
+	}
+
+	if receivedHeader != "test" {
+		t.Fatalf("Expected the headers to be sent, but got %s", receivedHeader)
+	}
+
+	if _, ok := headers[contentTypeHeader]; ok {
+		t.Fatalf("Expected the headers of the options to be left unchanged")
+	}
+}
@@ -0,0 +1,292 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const prometheusContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// DefaultLatencyBuckets are the upper bounds, in seconds, of the request latency histogram.
+var DefaultLatencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// InMemory is a Metrics implementation that aggregates observations in memory.
+//
+// It implements http.Handler, serving the aggregated metrics
+// in the Prometheus text exposition format.
+type InMemory struct {
+	buckets []float64
+
+	mutex    sync.Mutex
+	requests map[requestKey]*requestStats
+	latency  map[latencyKey]*histogram
+	hosts    map[hostKey]uint64
+	auth     map[authKey]uint64
+}
+
+type requestKey struct {
+	labels      Labels
+	method      string
+	endpoint    string
+	statusClass string
+}
+
+type latencyKey struct {
+	labels   Labels
+	method   string
+	endpoint string
+}
+
+type hostKey struct {
+	labels      Labels
+	host        string
+	statusClass string
+}
+
+type authKey struct {
+	labels    Labels
+	su        bool
+	errorType string
+}
+
+type requestStats struct {
+	count    uint64
+	bytesOut uint64
+	bytesIn  uint64
+	retries  uint64
+}
+
+type histogram struct {
+	counts []uint64 // Cumulative counts, one per bucket
+	count  uint64
+	sum    float64
+}
+
+// NewInMemory returns an in-memory Metrics implementation.
+//
+// Latency buckets are given in seconds and default to DefaultLatencyBuckets.
+func NewInMemory(latencyBuckets ...float64) *InMemory {
+	if len(latencyBuckets) == 0 {
+		latencyBuckets = DefaultLatencyBuckets
+	}
+
+	buckets := append([]float64(nil), latencyBuckets...)
+	sort.Float64s(buckets)
+
+	return &InMemory{
+		buckets:  buckets,
+		requests: map[requestKey]*requestStats{},
+		latency:  map[latencyKey]*histogram{},
+		hosts:    map[hostKey]uint64{},
+		auth:     map[authKey]uint64{},
+	}
+}
+
+// ObserveRequest records a completed request.
+func (m *InMemory) ObserveRequest(observation RequestObservation) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	key := requestKey{
+		labels:      observation.Labels,
+		method:      observation.Method,
+		endpoint:    observation.Endpoint,
+		statusClass: observation.StatusClass(),
+	}
+	stats, ok := m.requests[key]
+	if !ok {
+		stats = &requestStats{}
+		m.requests[key] = stats
+	}
+
+	stats.count++
+	stats.retries += uint64(observation.Retries)
+	if observation.BytesOut > 0 {
+		stats.bytesOut += uint64(observation.BytesOut)
+	}
+	if observation.BytesIn > 0 {
+		stats.bytesIn += uint64(observation.BytesIn)
+	}
+
+	if observation.Host != "" {
+		m.hosts[hostKey{observation.Labels, observation.Host, observation.StatusClass()}]++
+	}
+
+	if observation.Status == 0 {
+		return
+	}
+
+	latencyKey := latencyKey{observation.Labels, observation.Method, observation.Endpoint}
+	latency, ok := m.latency[latencyKey]
+	if !ok {
+		latency = &histogram{counts: make([]uint64, len(m.buckets))}
+		m.latency[latencyKey] = latency
+	}
+
+	seconds := observation.Duration.Seconds()
+	latency.count++
+	latency.sum += seconds
+	for i, bound := range m.buckets {
+		if seconds <= bound {
+			latency.counts[i]++
+		}
+	}
+}
+
+// ObserveAuthentication records a token issued or rejected by an authenticator.
+func (m *InMemory) ObserveAuthentication(observation AuthenticationObservation) {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	m.auth[authKey{observation.Labels, observation.Su, observation.ErrorType}]++
+}
+
+// ServeHTTP serves the metrics in the Prometheus text exposition format.
+func (m *InMemory) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", prometheusContentType)
+	_ = m.WritePrometheus(w)
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition format.
+func (m *InMemory) WritePrometheus(w io.Writer) error {
+	m.mutex.Lock()
+	defer m.mutex.Unlock()
+
+	writer := bufio.NewWriter(w)
+
+	requestKeys := make([]requestKey, 0, len(m.requests))
+	for key := range m.requests {
+		requestKeys = append(requestKeys, key)
+	}
+	sort.Slice(requestKeys, func(i, j int) bool {
+		return requestKeys[i].String() < requestKeys[j].String()
+	})
+
+	counters := []struct {
+		name  string
+		help  string
+		value func(*requestStats) uint64
+	}{
+		{"pusher_platform_requests_total", "Requests made to the platform.", func(s *requestStats) uint64 { return s.count }},
+		{"pusher_platform_request_bytes_total", "Request body bytes sent to the platform.", func(s *requestStats) uint64 { return s.bytesOut }},
+		{"pusher_platform_response_bytes_total", "Response body bytes received from the platform.", func(s *requestStats) uint64 { return s.bytesIn }},
+		{"pusher_platform_request_retries_total", "Retries of requests made to the platform.", func(s *requestStats) uint64 { return s.retries }},
+	}
+	for _, counter := range counters {
+		writeHeader(writer, counter.name, counter.help, "counter")
+		for _, key := range requestKeys {
+			fmt.Fprintf(writer, "%s{%s} %d\n", counter.name, key, counter.value(m.requests[key]))
+		}
+	}
+
+	latencyKeys := make([]latencyKey, 0, len(m.latency))
+	for key := range m.latency {
+		latencyKeys = append(latencyKeys, key)
+	}
+	sort.Slice(latencyKeys, func(i, j int) bool {
+		return latencyKeys[i].String() < latencyKeys[j].String()
+	})
+
+	name := "pusher_platform_request_duration_seconds"
+	writeHeader(writer, name, "Latency of requests made to the platform.", "histogram")
+	for _, key := range latencyKeys {
+		latency := m.latency[key]
+		for i, bound := range m.buckets {
+			fmt.Fprintf(writer, "%s_bucket{%s,le=\"%s\"} %d\n", name, key, formatFloat(bound), latency.counts[i])
+		}
+		fmt.Fprintf(writer, "%s_bucket{%s,le=\"+Inf\"} %d\n", name, key, latency.count)
+		fmt.Fprintf(writer, "%s_sum{%s} %s\n", name, key, formatFloat(latency.sum))
+		fmt.Fprintf(writer, "%s_count{%s} %d\n", name, key, latency.count)
+	}
+
+	hostKeys := make([]hostKey, 0, len(m.hosts))
+	for key := range m.hosts {
+		hostKeys = append(hostKeys, key)
+	}
+	sort.Slice(hostKeys, func(i, j int) bool {
+		return hostKeys[i].String() < hostKeys[j].String()
+	})
+
+	name = "pusher_platform_host_requests_total"
+	writeHeader(writer, name, "Requests made to the platform by the host that served them.", "counter")
+	for _, key := range hostKeys {
+		fmt.Fprintf(writer, "%s{%s} %d\n", name, key, m.hosts[key])
+	}
+
+	authKeys := make([]authKey, 0, len(m.auth))
+	for key := range m.auth {
+		authKeys = append(authKeys, key)
+	}
+	sort.Slice(authKeys, func(i, j int) bool {
+		return authKeys[i].String() < authKeys[j].String()
+	})
+
+	name = "pusher_platform_tokens_issued_total"
+	writeHeader(writer, name, "Tokens issued by the authenticator.", "counter")
+	for _, key := range authKeys {
+		if key.errorType == "" {
+			fmt.Fprintf(writer, "%s{%s,su=\"%t\"} %d\n", name, key.labels, key.su, m.auth[key])
+		}
+	}
+
+	name = "pusher_platform_authentication_rejects_total"
+	writeHeader(writer, name, "Authentication requests rejected by the authenticator.", "counter")
+	for _, key := range authKeys {
+		if key.errorType != "" {
+			fmt.Fprintf(writer, "%s{%s,error_type=\"%s\"} %d\n", name, key.labels, escape(key.errorType), m.auth[key])
+		}
+	}
+
+	return writer.Flush()
+}
+
+// String formats the labels for the Prometheus text exposition format.
+func (l Labels) String() string {
+	return fmt.Sprintf(
+		"service=\"%s\",version=\"%s\",cluster=\"%s\"",
+		escape(l.Service),
+		escape(l.Version),
+		escape(l.Cluster),
+	)
+}
+
+func (k requestKey) String() string {
+	return fmt.Sprintf(
+		"%s,method=\"%s\",endpoint=\"%s\",status_class=\"%s\"",
+		k.labels,
+		escape(k.method),
+		escape(k.endpoint),
+		k.statusClass,
+	)
+}
+
+func (k latencyKey) String() string {
+	return fmt.Sprintf("%s,method=\"%s\",endpoint=\"%s\"", k.labels, escape(k.method), escape(k.endpoint))
+}
+
+func (k hostKey) String() string {
+	return fmt.Sprintf("%s,host=\"%s\",status_class=\"%s\"", k.labels, escape(k.host), k.statusClass)
+}
+
+func (k authKey) String() string {
+	return fmt.Sprintf("%s,su=\"%t\",error_type=\"%s\"", k.labels, k.su, escape(k.errorType))
+}
+
+func writeHeader(writer io.Writer, name string, help string, metricType string) {
+	fmt.Fprintf(writer, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, metricType)
+}
+
+// escape escapes a label value for the Prometheus text exposition format.
+func escape(value string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(value)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
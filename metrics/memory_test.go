@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInMemoryPrometheusOutput(t *testing.T) {
+	m := NewInMemory(0.1, 1)
+	labels := Labels{Service: "chat", Version: "v1", Cluster: "us1"}
+
+	m.ObserveRequest(RequestObservation{
+		Labels:   labels,
+		Method:   http.MethodGet,
+		Endpoint: "/users",
+		Host:     "us1.pusherplatform.io",
+		Status:   http.StatusOK,
+		Duration: 50 * time.Millisecond,
+		BytesIn:  100,
+	})
+	m.ObserveRequest(RequestObservation{
+		Labels:   labels,
+		Method:   http.MethodGet,
+		Endpoint: "/users",
+		Status:   http.StatusOK,
+		Duration: 500 * time.Millisecond,
+		BytesIn:  50,
+		Retries:  2,
+	})
+	m.ObserveRequest(RequestObservation{
+		Labels:   labels,
+		Method:   http.MethodPost,
+		Endpoint: "/users",
+		Err:      errors.New("connection refused"),
+		BytesOut: 10,
+	})
+	m.ObserveAuthentication(AuthenticationObservation{Labels: labels, Su: true})
+
+	server := httptest.NewServer(m)
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatalf("Failed to scrape metrics with error: %+v", err)
+	}
+	defer response.Body.Close()
+
+	if contentType := response.Header.Get("Content-Type"); contentType != prometheusContentType {
+		t.Fatalf("Expected content type `%s`, but got `%s`", prometheusContentType, contentType)
+	}
+
+	var output strings.Builder
+	if err := m.WritePrometheus(&output); err != nil {
+		t.Fatalf("Expected no error when writing metrics, but got %+v", err)
+	}
+
+	get := `service="chat",version="v1",cluster="us1",method="GET",endpoint="/users"`
+	post := `service="chat",version="v1",cluster="us1",method="POST",endpoint="/users"`
+	expected := []string{
+		`pusher_platform_requests_total{` + get + `,status_class="2xx"} 2`,
+		`pusher_platform_requests_total{` + post + `,status_class="error"} 1`,
+		`pusher_platform_request_bytes_total{` + post + `,status_class="error"} 10`,
+		`pusher_platform_response_bytes_total{` + get + `,status_class="2xx"} 150`,
+		`pusher_platform_request_retries_total{` + get + `,status_class="2xx"} 2`,
+		`pusher_platform_request_duration_seconds_bucket{` + get + `,le="0.1"} 1`,
+		`pusher_platform_request_duration_seconds_bucket{` + get + `,le="1"} 2`,
+		`pusher_platform_request_duration_seconds_bucket{` + get + `,le="+Inf"} 2`,
+		`pusher_platform_request_duration_seconds_count{` + get + `} 2`,
+		`pusher_platform_host_requests_total{service="chat",version="v1",cluster="us1",host="us1.pusherplatform.io",status_class="2xx"} 1`,
+		`pusher_platform_tokens_issued_total{service="chat",version="v1",cluster="us1",su="true"} 1`,
+		"# TYPE pusher_platform_request_duration_seconds histogram",
+	}
+	for _, line := range expected {
+		if !strings.Contains(output.String(), line) {
+			t.Fatalf("Expected metrics to contain `%s`, but got:\n%s", line, output.String())
+		}
+	}
+
+	if strings.Contains(output.String(), "_count{"+post) {
+		t.Fatalf("Expected requests without a response to be excluded from the latency histogram")
+	}
+}
+
+func TestLabelsEscaping(t *testing.T) {
+	labels := Labels{Service: `a"b`, Version: `c\d`, Cluster: "e\nf"}
+	if actual := labels.String(); actual != `service="a\"b",version="c\\d",cluster="e\nf"` {
+		t.Fatalf("Unexpected escaped labels `%s`", actual)
+	}
+}
+
+func TestContextLabels(t *testing.T) {
+	ctx := ContextWithLabels(context.Background(), Labels{Service: "chat"})
+	labels := LabelsFromContext(ctx, Labels{Service: "default", Version: "v1"})
+	if labels.Service != "chat" || labels.Version != "v1" {
+		t.Fatalf("Expected context labels to take precedence over defaults, but got %+v", labels)
+	}
+
+	if _, ok := EndpointFromContext(ctx); ok {
+		t.Fatalf("Expected no endpoint in context")
+	}
+
+	endpoint, ok := EndpointFromContext(ContextWithEndpoint(ctx, "/users/:id"))
+	if !ok || endpoint != "/users/:id" {
+		t.Fatalf("Expected endpoint `/users/:id`, but got `%s`", endpoint)
+	}
+}
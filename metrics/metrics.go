@@ -0,0 +1,114 @@
+// Package metrics exposes the Metrics interface that clients and authenticators
+// report to, along with an in-memory implementation.
+//
+// The in-memory implementation can be served in the Prometheus text exposition
+// format, so it can be scraped without pulling in additional dependencies.
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"time"
+)
+
+// Labels identify the service a metric was recorded for.
+type Labels struct {
+	Service string // Service name
+	Version string // Service version
+	Cluster string // Cluster of the instance
+}
+
+// merge returns the labels with empty fields taken from the defaults.
+func (l Labels) merge(defaults Labels) Labels {
+	if l.Service == "" {
+		l.Service = defaults.Service
+	}
+
+	if l.Version == "" {
+		l.Version = defaults.Version
+	}
+
+	if l.Cluster == "" {
+		l.Cluster = defaults.Cluster
+	}
+
+	return l
+}
+
+// RequestObservation describes a completed request to the platform.
+type RequestObservation struct {
+	Labels   Labels
+	Method   string        // HTTP method of the request
+	Endpoint string        // Endpoint that was requested
+	Host     string        // Host that served the request
+	Status   int           // Status code of the response, 0 if no response was received
+	Duration time.Duration // Time until the response headers were received
+	BytesOut int64         // Number of request body bytes sent
+	BytesIn  int64         // Number of response body bytes received
+	Retries  int           // 1 if the request retried an earlier attempt of the call, 0 otherwise
+	Err      error         // Error returned for the request, if any
+}
+
+// StatusClass returns the class of the status code, such as `2xx`,
+// or `error` if no response was received.
+func (o RequestObservation) StatusClass() string {
+	if o.Status < 100 || o.Status > 599 {
+		return "error"
+	}
+
+	return strconv.Itoa(o.Status/100) + "xx"
+}
+
+// AuthenticationObservation describes a token issued or rejected by an authenticator.
+type AuthenticationObservation struct {
+	Labels    Labels
+	Su        bool   // Whether the token contains the `su` claim
+	ErrorType string // Type of error the request was rejected with, empty if a token was issued
+}
+
+// Metrics receives observations of requests and authentications.
+//
+// Implementations must be safe for concurrent use.
+type Metrics interface {
+	ObserveRequest(observation RequestObservation)
+	ObserveAuthentication(observation AuthenticationObservation)
+}
+
+type contextKey int
+
+const (
+	labelsContextKey contextKey = iota
+	endpointContextKey
+)
+
+// ContextWithLabels returns a context that carries the given labels.
+//
+// Labels in the context take precedence over those configured on a client.
+func ContextWithLabels(ctx context.Context, labels Labels) context.Context {
+	return context.WithValue(ctx, labelsContextKey, labels)
+}
+
+// LabelsFromContext returns the labels carried by the context,
+// with empty fields taken from the defaults.
+func LabelsFromContext(ctx context.Context, defaults Labels) Labels {
+	labels, _ := ctx.Value(labelsContextKey).(Labels)
+	return labels.merge(defaults)
+}
+
+// UnknownEndpoint is the endpoint requests are recorded under when no endpoint is set.
+const UnknownEndpoint = "unknown"
+
+// ContextWithEndpoint returns a context that carries the endpoint name
+// requests are recorded under, such as `/users/:id`.
+//
+// Requests made without an endpoint are recorded under UnknownEndpoint rather
+// than their path, so that a series is not recorded for every distinct path.
+func ContextWithEndpoint(ctx context.Context, endpoint string) context.Context {
+	return context.WithValue(ctx, endpointContextKey, endpoint)
+}
+
+// EndpointFromContext returns the endpoint carried by the context, if any.
+func EndpointFromContext(ctx context.Context) (string, bool) {
+	endpoint, ok := ctx.Value(endpointContextKey).(string)
+	return endpoint, ok && endpoint != ""
+}
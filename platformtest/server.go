@@ -0,0 +1,459 @@
+// Package platformtest provides an in-process fake of the Pusher platform
+// that an Instance can be pointed at in tests.
+//
+// The server scopes requests to services by name, version and instance ID,
+// verifies the tokens of requests against the instance key, and can simulate
+// latency and error responses.
+//
+//	server, err := platformtest.NewServer(platformtest.Options{})
+//	defer server.Close()
+//
+//	server.HandleFunc("chatkit", "v6", "/users", func(w http.ResponseWriter, r *http.Request) {
+//		...
+//	})
+//
+//	serviceInstance, err := instance.New(server.InstanceOptions("chatkit", "v6"))
+package platformtest
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	jwt "github.com/pusher/jwt-go"
+	"github.com/pusher/pusher-platform-go/client"
+	"github.com/pusher/pusher-platform-go/instance"
+)
+
+const (
+	defaultLocator      = "v1:test:instance-id"
+	defaultKey          = "key-id:key-secret"
+	servicesPath        = "/services/"
+	issuerPrefix        = "api_keys/"
+	authorizationHeader = "Authorization"
+	bearerPrefix        = "Bearer "
+	contentTypeHeader   = "Content-Type"
+	jsonContentType     = "application/json"
+)
+
+// Error types of the error responses sent by the server.
+const (
+	ErrorTypeNotFound          = "platformtest/not_found"
+	ErrorTypeInvalidToken      = "platformtest/invalid_token"
+	ErrorTypeInsufficientScope = "platformtest/insufficient_scope"
+	ErrorTypeSimulatedFailure  = "platformtest/simulated_failure"
+)
+
+// Options configures a Server.
+type Options struct {
+	Locator string // Instance locator served, defaults to v1:test:instance-id
+	Key     string // Instance key that tokens are verified with, defaults to key-id:key-secret
+}
+
+// HandlerOptions configures how the requests of a handler are authenticated.
+type HandlerOptions struct {
+	RequireSu bool // Only accept tokens with the su claim
+	Anonymous bool // Accept requests without a token
+}
+
+// Fault simulates latency or an error response for matching requests.
+type Fault struct {
+	Method  string        // Method of the affected requests, any method if empty
+	Path    string        // Unscoped path of the affected requests, any path if empty
+	Latency time.Duration // Delay before the request is handled
+	Status  int           // Status of the error response, requests are handled as usual if zero
+	Body    interface{}   // Body of the error response, defaults to a platform error body
+	Headers http.Header   // Headers of the error response, such as Retry-After
+	Times   int           // Number of requests affected, every request if zero
+}
+
+// Claims are the verified claims of the token a request was made with.
+type Claims struct {
+	Instance  string
+	Issuer    string
+	Subject   string
+	Su        bool
+	ExpiresAt time.Time
+	Raw       map[string]interface{} // All claims of the token, including service claims
+}
+
+// Server is a fake of the Pusher platform serving a single instance.
+type Server struct {
+	URL        string // Base URL of the server, of the form https://127.0.0.1:1234
+	Host       string // Host of the server, which a Client connects to
+	InstanceID string
+	Cluster    string
+
+	locator   string
+	key       string
+	keyID     string
+	keySecret string
+	server    *httptest.Server
+
+	mutex    sync.Mutex
+	services map[string]*http.ServeMux
+	faults   []*Fault
+}
+
+type contextKey int
+
+const claimsKey contextKey = iota
+
+// NewServer starts a Server serving the instance of the options.
+// It should be closed once it is no longer used.
+func NewServer(options Options) (*Server, error) {
+	if options.Locator == "" {
+		options.Locator = defaultLocator
+	}
+	if options.Key == "" {
+		options.Key = defaultKey
+	}
+
+	locatorComponents, err := instance.ParseInstanceLocator(options.Locator)
+	if err != nil {
+		return nil, err
+	}
+
+	keyComponents, err := instance.ParseKey(options.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Server{
+		InstanceID: locatorComponents.InstanceID,
+		Cluster:    locatorComponents.Cluster,
+		locator:    options.Locator,
+		key:        options.Key,
+		keyID:      keyComponents.Key,
+		keySecret:  keyComponents.Secret,
+		services:   map[string]*http.ServeMux{},
+	}
+
+	s.server = httptest.NewTLSServer(http.HandlerFunc(s.serveHTTP))
+	s.URL = s.server.URL
+
+	uri, err := url.Parse(s.server.URL)
+	if err != nil {
+		s.server.Close()
+		return nil, err
+	}
+	s.Host = uri.Host
+
+	return s, nil
+}
+
+// Close shuts down the server, blocking until all outstanding requests have completed.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Locator returns the instance locator served.
+func (s *Server) Locator() string {
+	return s.locator
+}
+
+// Key returns the instance key that tokens are verified with.
+func (s *Server) Key() string {
+	return s.key
+}
+
+// Client returns a Client connected to the server.
+func (s *Server) Client() client.Client {
+	return s.ClientWithOptions(client.Options{})
+}
+
+// ClientWithOptions returns a Client connected to the server
+// with the given options. The host, endpoint and TLS config are replaced.
+func (s *Server) ClientWithOptions(options client.Options) client.Client {
+	options.Host = s.Host
+	options.Endpoint = nil
+	options.TLSConfig = s.server.Client().Transport.(*http.Transport).TLSClientConfig
+
+	return client.New(options)
+}
+
+// InstanceOptions returns the options of an Instance of a service served by the server.
+func (s *Server) InstanceOptions(serviceName string, serviceVersion string) instance.Options {
+	return instance.Options{
+		Locator:        s.locator,
+		Key:            s.key,
+		ServiceName:    serviceName,
+		ServiceVersion: serviceVersion,
+		Client:         s.Client(),
+	}
+}
+
+// Handle registers the handler for the unscoped pattern of a service.
+// Requests must be made with a valid token.
+func (s *Server) Handle(serviceName string, serviceVersion string, pattern string, handler http.Handler) {
+	s.HandleWithOptions(serviceName, serviceVersion, pattern, handler, HandlerOptions{})
+}
+
+// HandleFunc registers the handler function for the unscoped pattern of a service.
+// Requests must be made with a valid token.
+func (s *Server) HandleFunc(
+	serviceName string,
+	serviceVersion string,
+	pattern string,
+	handler func(http.ResponseWriter, *http.Request),
+) {
+	s.Handle(serviceName, serviceVersion, pattern, http.HandlerFunc(handler))
+}
+
+// HandleWithOptions is like Handle but configures how requests are authenticated.
+//
+// The path of requests passed to the handler is unscoped, and the claims of
+// their token can be retrieved with ClaimsFromRequest.
+func (s *Server) HandleWithOptions(
+	serviceName string,
+	serviceVersion string,
+	pattern string,
+	handler http.Handler,
+	options HandlerOptions,
+) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	service := serviceName + "/" + serviceVersion
+	mux, ok := s.services[service]
+	if !ok {
+		mux = http.NewServeMux()
+		s.services[service] = mux
+	}
+
+	mux.Handle(pattern, s.authenticate(handler, options))
+}
+
+// AddFault simulates latency or an error response for the requests matching the fault.
+// Faults apply in the order they were added.
+func (s *Server) AddFault(fault Fault) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = append(s.faults, &fault)
+}
+
+// ClearFaults removes all faults.
+func (s *Server) ClearFaults() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.faults = nil
+}
+
+// ClaimsFromRequest returns the claims of the token of a request passed to a handler.
+func ClaimsFromRequest(r *http.Request) (Claims, bool) {
+	claims, ok := r.Context().Value(claimsKey).(Claims)
+	return claims, ok
+}
+
+// serveHTTP unscopes requests, applies faults and dispatches them to the handlers of their service.
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	serviceName, serviceVersion, instanceID, path, ok := parseScopedPath(r.URL.Path)
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorTypeNotFound, "Paths must be of the form /services/<name>/<version>/<instance-id>/...")
+		return
+	}
+
+	if instanceID != s.InstanceID {
+		writeError(w, http.StatusNotFound, ErrorTypeNotFound, fmt.Sprintf("Instance %s not found", instanceID))
+		return
+	}
+
+	if s.applyFault(w, r, path) {
+		return
+	}
+
+	s.mutex.Lock()
+	mux, ok := s.services[serviceName+"/"+serviceVersion]
+	s.mutex.Unlock()
+	if !ok {
+		writeError(w, http.StatusNotFound, ErrorTypeNotFound, fmt.Sprintf("Service %s %s not found", serviceName, serviceVersion))
+		return
+	}
+
+	unscoped := r.Clone(r.Context())
+	unscoped.URL.Path = path
+	unscoped.URL.RawPath = ""
+	unscoped.RequestURI = unscoped.URL.RequestURI()
+
+	if _, pattern := mux.Handler(unscoped); pattern == "" {
+		writeError(w, http.StatusNotFound, ErrorTypeNotFound, fmt.Sprintf("No handler for %s %s", r.Method, path))
+		return
+	}
+
+	mux.ServeHTTP(w, unscoped)
+}
+
+// applyFault simulates the first fault matching the request, and reports whether it responded.
+func (s *Server) applyFault(w http.ResponseWriter, r *http.Request, path string) bool {
+	fault, ok := s.matchFault(r.Method, path)
+	if !ok {
+		return false
+	}
+
+	if fault.Latency > 0 {
+		timer := time.NewTimer(fault.Latency)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return true
+		}
+	}
+
+	if fault.Status == 0 {
+		return false
+	}
+
+	for key, values := range fault.Headers {
+		w.Header()[key] = values
+	}
+
+	body := fault.Body
+	if body == nil {
+		body = errorBody(ErrorTypeSimulatedFailure, fmt.Sprintf("Simulated %d response", fault.Status))
+	}
+	writeJSON(w, fault.Status, body)
+
+	return true
+}
+
+// matchFault returns the first fault matching the request, using it up.
+func (s *Server) matchFault(method string, path string) (Fault, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for i, fault := range s.faults {
+		if (fault.Method != "" && fault.Method != method) || (fault.Path != "" && fault.Path != path) {
+			continue
+		}
+
+		if fault.Times > 0 {
+			fault.Times--
+			if fault.Times == 0 {
+				s.faults = append(s.faults[:i:i], s.faults[i+1:]...)
+			}
+		}
+
+		return *fault, true
+	}
+
+	return Fault{}, false
+}
+
+// authenticate wraps a handler to verify the token of requests against the instance key.
+func (s *Server) authenticate(handler http.Handler, options HandlerOptions) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization := r.Header.Get(authorizationHeader)
+		if authorization == "" && options.Anonymous {
+			handler.ServeHTTP(w, r)
+			return
+		}
+
+		if !strings.HasPrefix(authorization, bearerPrefix) {
+			writeError(w, http.StatusUnauthorized, ErrorTypeInvalidToken, "A Bearer token is required")
+			return
+		}
+
+		claims, err := s.verifyToken(strings.TrimPrefix(authorization, bearerPrefix))
+		if err != nil {
+			writeError(w, http.StatusUnauthorized, ErrorTypeInvalidToken, err.Error())
+			return
+		}
+
+		if options.RequireSu && !claims.Su {
+			writeError(w, http.StatusForbidden, ErrorTypeInsufficientScope, "A token with the su claim is required")
+			return
+		}
+
+		handler.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), claimsKey, claims)))
+	})
+}
+
+// verifyToken verifies the signature, expiry, instance and issuer of a token.
+func (s *Server) verifyToken(token string) (Claims, error) {
+	parsed, err := jwt.Parse(token, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("Unexpected signing method: %v", token.Header["alg"])
+		}
+
+		return []byte(s.keySecret), nil
+	})
+	if err != nil {
+		return Claims{}, fmt.Errorf("Invalid token: %s", err)
+	}
+
+	mapClaims, ok := parsed.Claims.(jwt.MapClaims)
+	if !ok || !parsed.Valid {
+		return Claims{}, errors.New("Invalid token")
+	}
+
+	expiresAt, ok := mapClaims["exp"].(float64)
+	if !ok {
+		return Claims{}, errors.New("Token has no exp claim")
+	}
+
+	claims := Claims{ExpiresAt: time.Unix(int64(expiresAt), 0), Raw: mapClaims}
+	claims.Instance, _ = mapClaims["instance"].(string)
+	claims.Issuer, _ = mapClaims["iss"].(string)
+	claims.Subject, _ = mapClaims["sub"].(string)
+	claims.Su, _ = mapClaims["su"].(bool)
+
+	if claims.Instance != s.InstanceID {
+		return Claims{}, fmt.Errorf("Token is for instance %q, not %q", claims.Instance, s.InstanceID)
+	}
+
+	if claims.Issuer != issuerPrefix+s.keyID {
+		return Claims{}, fmt.Errorf("Token is issued by %q, not %q", claims.Issuer, issuerPrefix+s.keyID)
+	}
+
+	return claims, nil
+}
+
+// parseScopedPath splits a path of the form /services/<name>/<version>/<instance-id>/<path>.
+func parseScopedPath(scopedPath string) (string, string, string, string, bool) {
+	if !strings.HasPrefix(scopedPath, servicesPath) {
+		return "", "", "", "", false
+	}
+
+	parts := strings.SplitN(strings.TrimPrefix(scopedPath, servicesPath), "/", 4)
+	if len(parts) < 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", "", false
+	}
+
+	path := "/"
+	if len(parts) == 4 {
+		path += parts[3]
+	}
+
+	return parts[0], parts[1], parts[2], path, true
+}
+
+// errorBody returns a platform error body.
+func errorBody(errorType string, description string) map[string]string {
+	return map[string]string{
+		"error":             errorType,
+		"error_description": description,
+	}
+}
+
+// writeError writes a platform error response.
+func writeError(w http.ResponseWriter, status int, errorType string, description string) {
+	writeJSON(w, status, errorBody(errorType, description))
+}
+
+// writeJSON writes a JSON response.
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set(contentTypeHeader, jsonContentType)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,227 @@
+package platformtest
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/pusher/pusher-platform-go/auth"
+	"github.com/pusher/pusher-platform-go/client"
+	"github.com/pusher/pusher-platform-go/instance"
+)
+
+func newTestServer(t *testing.T) (*Server, instance.Instance) {
+	server, err := NewServer(Options{})
+	if err != nil {
+		t.Fatalf("Expected no error starting the server, but got %+v", err)
+	}
+
+	serviceInstance, err := instance.New(server.InstanceOptions("test_service", "v1"))
+	if err != nil {
+		t.Fatalf("Expected no error constructing an instance, but got %+v", err)
+	}
+
+	return server, serviceInstance
+}
+
+func generateToken(t *testing.T, serviceInstance instance.Instance, options auth.Options) *string {
+	token, err := serviceInstance.GenerateAccessToken(options)
+	if err != nil {
+		t.Fatalf("Expected no error generating a token, but got %+v", err)
+	}
+
+	return &token.Token
+}
+
+func TestServerAuthentication(t *testing.T) {
+	server, serviceInstance := newTestServer(t)
+	defer server.Close()
+
+	var receivedClaims Claims
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if claims, ok := ClaimsFromRequest(r); ok && claims.Su {
+			receivedClaims = claims
+		}
+		w.Write([]byte(r.URL.Path))
+	})
+	server.Handle("test_service", "v1", "/users", handler)
+	server.HandleWithOptions("test_service", "v1", "/admin", handler, HandlerOptions{RequireSu: true})
+	server.HandleWithOptions("test_service", "v1", "/public", handler, HandlerOptions{Anonymous: true})
+
+	userID := "alice"
+	userToken := generateToken(t, serviceInstance, auth.Options{UserID: &userID})
+	suToken := generateToken(t, serviceInstance, auth.Options{Su: true})
+
+	otherInstance, _ := instance.New(instance.Options{
+		Locator:        server.Locator(),
+		Key:            "key-id:wrong-secret",
+		ServiceName:    "test_service",
+		ServiceVersion: "v1",
+	})
+	forgedToken := generateToken(t, otherInstance, auth.Options{Su: true})
+
+	expiry := -time.Minute
+	expiredToken := generateToken(t, serviceInstance, auth.Options{Su: true, TokenExpiry: &expiry})
+
+	testCases := []struct {
+		name     string
+		path     string
+		jwt      *string
+		expected error
+	}{
+		{"user token", "/users", userToken, nil},
+		{"su token", "/admin", suToken, nil},
+		{"anonymous", "/public", nil, nil},
+		{"missing token", "/users", nil, client.ErrUnauthorized},
+		{"forged token", "/users", forgedToken, client.ErrUnauthorized},
+		{"expired token", "/users", expiredToken, client.ErrUnauthorized},
+		{"token without su", "/admin", userToken, client.ErrForbidden},
+		{"unknown path", "/rooms", suToken, client.ErrNotFound},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			response, err := serviceInstance.Request(context.Background(), client.RequestOptions{
+				Method: http.MethodGet,
+				Path:   testCase.path,
+				Jwt:    testCase.jwt,
+			})
+
+			if testCase.expected != nil {
+				if !errors.Is(err, testCase.expected) {
+					t.Fatalf("Expected %v, but got %+v", testCase.expected, err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Expected no error, but got %+v", err)
+			}
+			defer response.Body.Close()
+
+			body, _ := ioutil.ReadAll(response.Body)
+			if string(body) != testCase.path {
+				t.Fatalf("Expected the handler to receive the unscoped path %s, but got %s", testCase.path, body)
+			}
+		})
+	}
+
+	if receivedClaims.Subject != "" || !receivedClaims.Su || receivedClaims.Instance != server.InstanceID {
+		t.Fatalf("Expected the claims of the su token, but got %+v", receivedClaims)
+	}
+}
+
+func TestServerScoping(t *testing.T) {
+	server, _ := newTestServer(t)
+	defer server.Close()
+
+	server.HandleWithOptions("test_service", "v1", "/users", http.NotFoundHandler(), HandlerOptions{Anonymous: true})
+
+	for _, options := range []instance.Options{
+		{Locator: "v1:test:other-instance", ServiceName: "test_service", ServiceVersion: "v1"},
+		{Locator: server.Locator(), ServiceName: "test_service", ServiceVersion: "v2"},
+	} {
+		options.Key = server.Key()
+		options.Client = server.Client()
+		serviceInstance, _ := instance.New(options)
+
+		_, err := serviceInstance.Request(context.Background(), client.RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/users",
+		})
+
+		var platformError *client.PlatformError
+		if !errors.As(err, &platformError) || platformError.ErrorType != ErrorTypeNotFound {
+			t.Fatalf("Expected a not found error, but got %+v", err)
+		}
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	server, serviceInstance := newTestServer(t)
+	defer server.Close()
+
+	server.HandleWithOptions("test_service", "v1", "/users", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}), HandlerOptions{Anonymous: true})
+
+	server.AddFault(Fault{Path: "/users", Status: http.StatusServiceUnavailable, Times: 1})
+	server.AddFault(Fault{Method: http.MethodGet, Latency: 100 * time.Millisecond})
+
+	request := func() error {
+		response, err := serviceInstance.Request(context.Background(), client.RequestOptions{
+			Method: http.MethodGet,
+			Path:   "/users",
+		})
+		if err == nil {
+			response.Body.Close()
+		}
+
+		return err
+	}
+
+	if err := request(); !errors.Is(err, client.ErrServiceUnavailable) {
+		t.Fatalf("Expected a service unavailable error, but got %+v", err)
+	}
+
+	start := time.Now()
+	if err := request(); err != nil {
+		t.Fatalf("Expected no error once the fault was used up, but got %+v", err)
+	}
+
+	if elapsed := time.Since(start); elapsed < 100*time.Millisecond {
+		t.Fatalf("Expected the request to be delayed, but it took %s", elapsed)
+	}
+
+	server.ClearFaults()
+	start = time.Now()
+	if err := request(); err != nil || time.Since(start) >= 100*time.Millisecond {
+		t.Fatalf("Expected the faults to be cleared, but got %+v after %s", err, time.Since(start))
+	}
+}
+
+func TestServerSubscriptions(t *testing.T) {
+	server, serviceInstance := newTestServer(t)
+	defer server.Close()
+
+	server.HandleWithOptions("test_service", "v1", "/rooms/1", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		subscription, err := NewSubscriptionWriter(w)
+		if err != nil {
+			return
+		}
+
+		subscription.KeepAlive()
+		subscription.Event("1", map[string]string{"X-Event": "message"}, map[string]string{"text": "hello"})
+		subscription.End(http.StatusServiceUnavailable, nil, map[string]string{"error": "restarting"})
+	}), HandlerOptions{Anonymous: true})
+
+	subscription, err := serviceInstance.Subscribe(context.Background(), client.RequestOptions{
+		Path: "/rooms/1",
+	})
+	if err != nil {
+		t.Fatalf("Expected no error subscribing, but got %+v", err)
+	}
+	defer subscription.Close()
+
+	select {
+	case event := <-subscription.Events():
+		if event.ID != "1" || event.Headers.Get("X-Event") != "message" || string(event.Body) != `{"text":"hello"}` {
+			t.Fatalf("Expected the event to be received, but got %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for the event")
+	}
+
+	select {
+	case err := <-subscription.Errors():
+		var eos *client.EOS
+		if !errors.As(err, &eos) || eos.Status != http.StatusServiceUnavailable {
+			t.Fatalf("Expected the end of subscription, but got %+v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Timed out waiting for the end of subscription")
+	}
+}
@@ -0,0 +1,80 @@
+package platformtest
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// Message types of the elements subscription protocol.
+const (
+	messageTypeKeepAlive = 0
+	messageTypeEvent     = 1
+	messageTypeEOS       = 255
+)
+
+// SubscriptionWriter writes the messages of a streaming subscription to a response,
+// flushing each message so it is delivered straight away.
+//
+//	server.HandleFunc("chatkit", "v6", "/rooms/1", func(w http.ResponseWriter, r *http.Request) {
+//		subscription, err := platformtest.NewSubscriptionWriter(w)
+//		...
+//		subscription.Event("1", nil, message)
+//		subscription.End(http.StatusOK, nil, nil)
+//	})
+type SubscriptionWriter struct {
+	writer  http.ResponseWriter
+	flusher http.Flusher
+}
+
+// NewSubscriptionWriter starts a subscription by responding with a 200 status.
+func NewSubscriptionWriter(w http.ResponseWriter) (*SubscriptionWriter, error) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return nil, errors.New("Response writer does not support flushing")
+	}
+
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	return &SubscriptionWriter{w, flusher}, nil
+}
+
+// KeepAlive writes a keep-alive message.
+func (s *SubscriptionWriter) KeepAlive() error {
+	return s.write(messageTypeKeepAlive, "")
+}
+
+// Event writes an event with the given ID, headers and JSON body.
+func (s *SubscriptionWriter) Event(id string, headers map[string]string, body interface{}) error {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	return s.write(messageTypeEvent, id, headers, body)
+}
+
+// End writes the end of subscription message with the given status, headers and info.
+// No messages should be written afterwards.
+func (s *SubscriptionWriter) End(status int, headers map[string]string, info interface{}) error {
+	if headers == nil {
+		headers = map[string]string{}
+	}
+
+	return s.write(messageTypeEOS, status, headers, info)
+}
+
+// write writes a message as a line of JSON and flushes it.
+func (s *SubscriptionWriter) write(message ...interface{}) error {
+	line, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+
+	if _, err := s.writer.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+
+	return nil
+}
@@ -0,0 +1,127 @@
+package tracing
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Headers of the W3C trace context specification.
+// See: https://www.w3.org/TR/trace-context/
+const (
+	TraceParentHeader = "traceparent"
+	TraceStateHeader  = "tracestate"
+)
+
+const traceParentVersion = "00"
+
+// FlagSampled indicates the trace is being recorded.
+const FlagSampled byte = 0x01
+
+// SpanContext identifies a span within a trace, as propagated
+// with the W3C traceparent and tracestate headers.
+type SpanContext struct {
+	TraceID    [16]byte
+	SpanID     [8]byte
+	Flags      byte
+	TraceState string // Vendor specific trace state, propagated as is
+}
+
+// IsValid reports whether the trace and span IDs are set.
+func (s SpanContext) IsValid() bool {
+	return s.TraceID != [16]byte{} && s.SpanID != [8]byte{}
+}
+
+// Sampled reports whether the trace is being recorded.
+func (s SpanContext) Sampled() bool {
+	return s.Flags&FlagSampled != 0
+}
+
+// TraceParent formats the span context as a traceparent header value.
+func (s SpanContext) TraceParent() string {
+	return fmt.Sprintf(
+		"%s-%s-%s-%02x",
+		traceParentVersion,
+		hex.EncodeToString(s.TraceID[:]),
+		hex.EncodeToString(s.SpanID[:]),
+		s.Flags,
+	)
+}
+
+// ParseTraceParent parses a traceparent header value.
+func ParseTraceParent(traceParent string) (SpanContext, error) {
+	parts := strings.Split(strings.TrimSpace(traceParent), "-")
+	if len(parts) < 4 {
+		return SpanContext{}, errors.New("Traceparent must be of the format <version>-<trace-id>-<parent-id>-<flags>")
+	}
+
+	version := parts[0]
+	if len(version) != 2 || version == "ff" || (version == traceParentVersion && len(parts) != 4) {
+		return SpanContext{}, fmt.Errorf("Unsupported traceparent version: %s", version)
+	}
+
+	var spanContext SpanContext
+	if err := decodeHex(parts[1], spanContext.TraceID[:]); err != nil {
+		return SpanContext{}, fmt.Errorf("Invalid trace ID: %s", err)
+	}
+
+	if err := decodeHex(parts[2], spanContext.SpanID[:]); err != nil {
+		return SpanContext{}, fmt.Errorf("Invalid parent ID: %s", err)
+	}
+
+	var flags [1]byte
+	if err := decodeHex(parts[3], flags[:]); err != nil {
+		return SpanContext{}, fmt.Errorf("Invalid trace flags: %s", err)
+	}
+	spanContext.Flags = flags[0]
+
+	if !spanContext.IsValid() {
+		return SpanContext{}, errors.New("Trace ID and parent ID must not be zero")
+	}
+
+	return spanContext, nil
+}
+
+// Inject sets the traceparent and tracestate headers from the span context.
+// Nothing is set for invalid span contexts.
+func Inject(headers http.Header, spanContext SpanContext) {
+	if !spanContext.IsValid() {
+		return
+	}
+
+	headers.Set(TraceParentHeader, spanContext.TraceParent())
+	if spanContext.TraceState != "" {
+		headers.Set(TraceStateHeader, spanContext.TraceState)
+	} else {
+		headers.Del(TraceStateHeader)
+	}
+}
+
+// Extract reads the span context from the traceparent and tracestate headers.
+func Extract(headers http.Header) (SpanContext, error) {
+	spanContext, err := ParseTraceParent(headers.Get(TraceParentHeader))
+	if err != nil {
+		return SpanContext{}, err
+	}
+
+	spanContext.TraceState = strings.Join(headers.Values(TraceStateHeader), ",")
+
+	return spanContext, nil
+}
+
+// decodeHex decodes a lowercase hex string that must exactly fill the destination.
+func decodeHex(value string, destination []byte) error {
+	if len(value) != hex.EncodedLen(len(destination)) {
+		return fmt.Errorf("expected %d hex characters, got %d", hex.EncodedLen(len(destination)), len(value))
+	}
+
+	if !bytes.Equal([]byte(value), bytes.ToLower([]byte(value))) {
+		return errors.New("hex characters must be lowercase")
+	}
+
+	_, err := hex.Decode(destination, []byte(value))
+	return err
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"testing"
+)
+
+const testTraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+
+func TestParseTraceParent(t *testing.T) {
+	spanContext, err := ParseTraceParent(testTraceParent)
+	if err != nil {
+		t.Fatalf("Expected no error when parsing traceparent, but got %+v", err)
+	}
+
+	if !spanContext.IsValid() || !spanContext.Sampled() {
+		t.Fatalf("Expected a valid sampled span context, but got %+v", spanContext)
+	}
+
+	if spanContext.TraceParent() != testTraceParent {
+		t.Fatalf("Expected traceparent `%s`, but got `%s`", testTraceParent, spanContext.TraceParent())
+	}
+
+	invalid := []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra",
+	}
+	for _, traceParent := range invalid {
+		if _, err := ParseTraceParent(traceParent); err == nil {
+			t.Fatalf("Expected an error when parsing `%s`", traceParent)
+		}
+	}
+
+	if _, err := ParseTraceParent("01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra"); err != nil {
+		t.Fatalf("Expected future versions with extra fields to be parsed, but got %+v", err)
+	}
+}
+
+func TestInjectExtract(t *testing.T) {
+	spanContext, _ := ParseTraceParent(testTraceParent)
+	spanContext.TraceState = "vendor=value"
+
+	headers := http.Header{}
+	Inject(headers, spanContext)
+	if headers.Get(TraceParentHeader) != testTraceParent || headers.Get(TraceStateHeader) != "vendor=value" {
+		t.Fatalf("Unexpected headers %v", headers)
+	}
+
+	extracted, err := Extract(headers)
+	if err != nil {
+		t.Fatalf("Expected no error when extracting, but got %+v", err)
+	}
+
+	if extracted != spanContext {
+		t.Fatalf("Expected extracted span context %+v, but got %+v", spanContext, extracted)
+	}
+
+	empty := http.Header{}
+	Inject(empty, SpanContext{})
+	if len(empty) != 0 {
+		t.Fatalf("Expected nothing to be injected for invalid span contexts, but got %v", empty)
+	}
+}
+
+func TestNoopTracerPropagatesContext(t *testing.T) {
+	spanContext, _ := ParseTraceParent(testTraceParent)
+	ctx := ContextWithSpanContext(context.Background(), spanContext)
+
+	_, span := NoopTracer{}.Start(ctx, "test")
+	defer span.End()
+
+	if span.SpanContext() != spanContext {
+		t.Fatalf("Expected the no-op span to carry the context's span context")
+	}
+}
@@ -0,0 +1,71 @@
+// Package tracing exposes the Tracer interface that clients start spans with,
+// along with W3C trace context propagation.
+//
+// Implementations for tracing libraries can be provided by adapting
+// their tracers to the Tracer interface, so no tracing library is
+// required by this package.
+package tracing
+
+import (
+	"context"
+	"time"
+)
+
+// Tracer starts spans for requests made to the platform.
+type Tracer interface {
+	// Start starts a span with the given name as a child of the span in the context.
+	// The returned context carries the new span.
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
+
+// Span represents a single operation within a trace.
+//
+// Implementations must be safe for concurrent use, since timing events
+// may be added from the goroutines performing the request.
+type Span interface {
+	SetAttribute(key string, value interface{}) // Sets an attribute of the span
+	AddEvent(name string, timestamp time.Time)  // Records a timed event within the span
+	RecordError(err error)                      // Marks the span as failed
+	SpanContext() SpanContext                   // Returns the trace context of the span
+	End()                                       // Completes the span
+}
+
+// NoopTracer is a Tracer that does not record anything.
+type NoopTracer struct{}
+
+// Start returns the context unchanged along with a span that does nothing.
+//
+// The span carries the trace context of the context, so it is still
+// propagated to outgoing requests.
+func (NoopTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	return ctx, noopSpan{SpanContextFromContext(ctx)}
+}
+
+type noopSpan struct {
+	spanContext SpanContext
+}
+
+func (noopSpan) SetAttribute(key string, value interface{}) {}
+func (noopSpan) AddEvent(name string, timestamp time.Time)  {}
+func (noopSpan) RecordError(err error)                      {}
+func (noopSpan) End()                                       {}
+
+func (s noopSpan) SpanContext() SpanContext {
+	return s.spanContext
+}
+
+type contextKey int
+
+const spanContextKey contextKey = iota
+
+// ContextWithSpanContext returns a context that carries the given trace context.
+func ContextWithSpanContext(ctx context.Context, spanContext SpanContext) context.Context {
+	return context.WithValue(ctx, spanContextKey, spanContext)
+}
+
+// SpanContextFromContext returns the trace context carried by the context,
+// which is invalid if there is none.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	spanContext, _ := ctx.Value(spanContextKey).(SpanContext)
+	return spanContext
+}